@@ -3,6 +3,9 @@ package ikgo
 import (
 	"bufio"
 	"container/list"
+	"io"
+	"time"
+	"unicode/utf8"
 )
 
 const (
@@ -18,74 +21,115 @@ const (
 type AnalyzeContext struct {
 	segmentBuff                  []rune
 	charType                     []int
+	byteOffsets                  []int // segmentBuff中每个字符在reader中的字节位置，多出的一位记录最后一个字符的结束位置
+	utf16Offsets                 []int // 同上，以UTF-16码元计
 	bufOffset, cursor, available int
 	buffLocker                   map[string]bool
 	orgLexemes                   *QuickSortSet
 	pathMap                      map[int]*LexemePath
 	results                      *list.List
 	smart                        bool
+	dict                         *Dictionary
+	dicts                        *dictSet // 本轮分词使用的词典快照
+	useStopwords                 bool     // 是否过滤停止词
+	usePersonNames               bool     // 是否识别人名
+	usePlaceOrgNames             bool     // 是否识别地名、机构名
+	useSubTokens                 bool     // 是否输出实体的组成部分
+	quantifierPolicy             QuantifierPolicy
+	useNumeralFilter             bool // 是否将中文数词替换为阿拉伯数字
+	useTemporal                  bool // 是否识别日期、时刻及时长
+	referenceTime                time.Time
+	exhaustCritical              int  // 缓冲区临界区大小
+	useHMM                       bool // 是否识别未登录词
+	hmm                          *HMMModel
+	oovStart, oovEnd             int // 等待未登录词识别的连续中文单字
 }
 
 func NewAnalyzeContext(smart bool) (ac *AnalyzeContext) {
+	return newAnalyzeContext(smart, defaultDictionary(), AC_BUFF_SIZE)
+}
+
+func newAnalyzeContext(smart bool, dict *Dictionary, bufferSize int) (ac *AnalyzeContext) {
 	ac = &AnalyzeContext{
-		smart:       smart,
-		segmentBuff: make([]rune, AC_BUFF_SIZE),
-		charType:    make([]int, AC_BUFF_SIZE),
-		buffLocker:  make(map[string]bool),
-		orgLexemes:  &QuickSortSet{},
-		pathMap:     make(map[int]*LexemePath),
-		results:     list.New(),
-		bufOffset:   0,
-		cursor:      0,
-		available:   0,
+		smart:           smart,
+		dict:            dict,
+		useStopwords:    true,
+		referenceTime:   time.Now(),
+		oovStart:        -1,
+		oovEnd:          -1,
+		exhaustCritical: min(AC_BUFF_EXHAUST_CRITICAL, bufferSize/2),
+		segmentBuff:     make([]rune, bufferSize),
+		charType:        make([]int, bufferSize),
+		byteOffsets:     make([]int, bufferSize+1),
+		utf16Offsets:    make([]int, bufferSize+1),
+		buffLocker:      make(map[string]bool),
+		orgLexemes:      &QuickSortSet{},
+		pathMap:         make(map[int]*LexemePath),
+		results:         list.New(),
+		bufOffset:       0,
+		cursor:          0,
+		available:       0,
 	}
 	return
 }
 
 /**
  * 增加一个读rune的函数
+ * 读满data或遇到错误时返回，reader读完时err为io.EOF
+ * byteOffsets、utf16Offsets与data对齐，调用前第0位已是第一个字符的起始位置，
+ * 每读入一个字符，在下一位记录它的结束位置
  */
-func readRunes(r *bufio.Reader, data []rune) (count, len int) {
-	limit := cap(data)
-	count = 0
-	for {
-		rn, width, err := r.ReadRune()
+func readRunes(r *bufio.Reader, data []rune, byteOffsets, utf16Offsets []int) (count int, err error) {
+	limit := len(data)
+	for count < limit {
+		var rn rune
+		var width int
+		rn, width, err = r.ReadRune()
 		if err != nil {
 			return
 		}
 		data[count] = rn
-		len += width
+		byteOffsets[count+1] = byteOffsets[count] + width
+		utf16Offsets[count+1] = utf16Offsets[count] + utf16Len(rn)
 		count += 1
-		if count == limit {
-			return
-		}
 	}
+	return
+}
+
+/**
+ * 字符编码为UTF-16时占用的码元数
+ */
+func utf16Len(r rune) int {
+	if r >= 0x10000 && r <= utf8.MaxRune {
+		return 2
+	}
+	return 1
 }
 
 /**
  * 根据context的上下文情况，填充segmentBuff
+ * 上一轮cursor之后尚未处理的字符移到buff头部，再从reader读入数据补满buff
  * @param reader
- * @return 返回待分析的（有效的）字串长度
+ * @return 返回待分析的（有效的）字串长度，以及reader返回的非io.EOF错误
  */
-func (ac *AnalyzeContext) fillBuffer(r *bufio.Reader) int {
-	var readCount int = 0
-	if ac.bufOffset == 0 {
-		readCount, _ = readRunes(r, ac.segmentBuff)
-	} else {
-		offset := ac.available - ac.cursor - 1
-		if offset > 0 {
-			for i := 0; i < offset; i++ {
-				ac.segmentBuff[i] = ac.segmentBuff[i+ac.cursor]
-			}
-			readCount = offset
-		}
-		// FIXED ME!
-		rc, _ := readRunes(r, ac.segmentBuff[offset:])
-		readCount += rc
+func (ac *AnalyzeContext) fillBuffer(r *bufio.Reader) (int, error) {
+	//每轮分词开始时取得词典快照，本轮内不受词典重新加载的影响
+	ac.dicts = ac.dict.load()
+
+	offset := 0
+	if ac.available > 0 {
+		offset = ac.available - ac.cursor - 1
+		copy(ac.segmentBuff, ac.segmentBuff[ac.cursor+1:ac.available])
+		copy(ac.byteOffsets, ac.byteOffsets[ac.cursor+1:ac.available+1])
+		copy(ac.utf16Offsets, ac.utf16Offsets[ac.cursor+1:ac.available+1])
+	}
+	readCount, err := readRunes(r, ac.segmentBuff[offset:], ac.byteOffsets[offset:], ac.utf16Offsets[offset:])
+	if err == io.EOF {
+		err = nil
 	}
-	ac.available = readCount
+	ac.available = offset + readCount
 	ac.cursor = 0
-	return readCount
+	return ac.available, err
 }
 
 /**
@@ -151,24 +195,25 @@ func (ac *AnalyzeContext) isBufferConsumed() bool {
  * 判断segmentBuff是否需要读取新数据
  *
  * 满足一下条件时，
- * 1.available == BUFF_SIZE 表示buffer满载
+ * 1.available == len(segmentBuff) 表示buffer满载
  * 2.buffIndex < available - 1 && buffIndex > available - BUFF_EXHAUST_CRITICAL表示当前指针处于临界区内
  * 3.!context.isBufferLocked()表示没有segmenter在占用buffer
  * 要中断当前循环（buffer要进行移位，并再读取数据的操作）
  * @return
  */
 func (ac *AnalyzeContext) needRefillBuffer() bool {
-	return ac.available == AC_BUFF_SIZE &&
+	return ac.available == len(ac.segmentBuff) &&
 		ac.cursor < ac.available-1 &&
-		ac.cursor > ac.available-AC_BUFF_EXHAUST_CRITICAL &&
+		ac.cursor > ac.available-ac.exhaustCritical &&
 		!ac.isBufferLocked()
 }
 
 /**
  * 累计当前的segmentBuff相对于reader起始位置的位移
+ * cursor及之前的字符已经处理完毕，下一轮buff从cursor+1开始
  */
 func (ac *AnalyzeContext) markBufferOffset() {
-	ac.bufOffset += ac.cursor
+	ac.bufOffset += ac.cursor + 1
 }
 
 /**
@@ -215,6 +260,51 @@ func (ac *AnalyzeContext) outputSingleCJK(index int) {
 	}
 }
 
+/**
+ * 输出未被词元覆盖的单字
+ * 启用未登录词识别时，连续的中文单字先暂存，由flushOOV统一切分输出
+ * @param index
+ */
+func (ac *AnalyzeContext) outputSingle(index int) {
+	if ac.useHMM && CHAR_CHINESE == ac.charType[index] {
+		if ac.oovEnd != index {
+			ac.flushOOV()
+			ac.oovStart = index
+		}
+		ac.oovEnd = index + 1
+		return
+	}
+	ac.flushOOV()
+	ac.outputSingleCJK(index)
+}
+
+/**
+ * 用隐马尔可夫模型切分暂存的连续中文单字
+ */
+func (ac *AnalyzeContext) flushOOV() {
+	start, end := ac.oovStart, ac.oovEnd
+	ac.oovStart, ac.oovEnd = -1, -1
+	if end-start <= 1 {
+		if start >= 0 {
+			ac.outputSingleCJK(start)
+		}
+		return
+	}
+
+	model := ac.hmm
+	if model == nil {
+		model = ac.dicts.hmmModel()
+	}
+	for _, length := range model.cut(ac.segmentBuff[start:end]) {
+		lexemeType := LEXEME_TYPE_OOV
+		if length == 1 {
+			lexemeType = LEXEME_TYPE_CNCHAR
+		}
+		ac.results.PushBack(NewLexeme(ac.bufOffset, start, length, lexemeType))
+		start += length
+	}
+}
+
 /**
  * 推送分词结果到结果集合
  * 1.从buff头部遍历到this.cursor已处理位置
@@ -225,32 +315,41 @@ func (ac *AnalyzeContext) outputToResult() {
 	var index int = 0
 	for index <= ac.cursor {
 		//跳过非CJK字符
-		if CHAR_USELESS == ac.charType[index] {
+		//自定义子分词器输出的词元可能从CHAR_USELESS字符开始，如$AAPL，需先查找pathMap
+		p, exists := ac.pathMap[index]
+		if (!exists || p == nil) && CHAR_USELESS == ac.charType[index] {
 			index++
 			continue
 		}
 
 		//从pathMap找出对应index位置的LexemePath
-		if p, exists := ac.pathMap[index]; exists && p != nil {
+		if exists && p != nil {
 			//输出LexemePath中的lexeme到results集合
 			l := p.set.pollFirst()
 			for l != nil {
+				ac.flushOOV()
 				ac.results.PushBack(l)
+				if ac.useSubTokens {
+					for _, part := range l.parts {
+						ac.results.PushBack(part)
+					}
+				}
 				index = l.begin + l.length
 				l = p.set.pollFirst()
 				if l != nil {
 					for ; index < l.begin; index++ {
-						ac.outputSingleCJK(index)
+						ac.outputSingle(index)
 					}
 				}
 			}
 		} else {
 			//pathMap中找不到index对应的LexemePath
 			//单字输出
-			ac.outputSingleCJK(index)
+			ac.outputSingle(index)
 			index++
 		}
 	}
+	ac.flushOOV()
 	ac.pathMap = make(map[int]*LexemePath)
 }
 
@@ -258,7 +357,7 @@ func (ac *AnalyzeContext) outputToResult() {
  * 组合词元
  */
 func (ac *AnalyzeContext) compound(l *Lexeme) {
-	if !ac.smart {
+	if !ac.smart || ac.quantifierPolicy == QUANTIFIER_POLICY_SPLIT {
 		return
 	}
 
@@ -312,7 +411,7 @@ func (ac *AnalyzeContext) getNextLexeme() (l *Lexeme) {
 
 	for result != nil {
 		ac.compound(result)
-		if isStopWord(ac.segmentBuff, result.begin, result.length) {
+		if ac.useStopwords && ac.dicts.isStopWord(ac.segmentBuff, result.begin, result.length) {
 			//是停止词继续取列表的下一个
 			el := ac.results.Front()
 			if el == nil {
@@ -324,6 +423,12 @@ func (ac *AnalyzeContext) getNextLexeme() (l *Lexeme) {
 		} else {
 			//不是停止词, 生成lexeme的词元文本,输出
 			result.lexemeText = string(ac.segmentBuff[result.begin : result.begin+result.length])
+			result.byteBegin = ac.byteOffsets[result.begin]
+			result.byteEnd = ac.byteOffsets[result.begin+result.length]
+			result.utf16Begin = ac.utf16Offsets[result.begin]
+			result.utf16End = ac.utf16Offsets[result.begin+result.length]
+			ac.tagLexeme(result)
+			ac.normalizeNumeral(result)
 			break
 		}
 	}
@@ -339,9 +444,13 @@ func (ac *AnalyzeContext) reset() {
 	ac.orgLexemes = &QuickSortSet{}
 	ac.available = 0
 	ac.bufOffset = 0
-	ac.charType = make([]int, AC_BUFF_SIZE)
+	size := len(ac.segmentBuff)
+	ac.charType = make([]int, size)
 	ac.cursor = 0
 	ac.results = list.New()
-	ac.segmentBuff = make([]rune, AC_BUFF_SIZE)
+	ac.segmentBuff = make([]rune, size)
+	ac.byteOffsets = make([]int, size+1)
+	ac.utf16Offsets = make([]int, size+1)
 	ac.pathMap = make(map[int]*LexemePath)
+	ac.oovStart, ac.oovEnd = -1, -1
 }
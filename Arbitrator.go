@@ -0,0 +1,94 @@
+package ikgo
+
+/**
+ * 歧义处理策略
+ * 智能分词模式下，每组相互交叉的词元（交叉路径）交给Arbitrator选出一组互不交叉的词元输出，
+ * 未被选中的位置上的中文字符按单字输出
+ */
+type Arbitrator interface {
+	/**
+	 * 从交叉路径中选出互不交叉的词元
+	 * @param path 交叉路径，至少包含两个词元
+	 * @return 选中的词元，必须取自path.Lexemes()且互不交叉，否则改用IK默认策略的结果
+	 */
+	Arbitrate(path *CrossPath) []*Lexeme
+}
+
+/**
+ * 函数形式的Arbitrator
+ */
+type ArbitratorFunc func(path *CrossPath) []*Lexeme
+
+func (f ArbitratorFunc) Arbitrate(path *CrossPath) []*Lexeme {
+	return f(path)
+}
+
+/**
+ * 一组相互交叉的词元
+ */
+type CrossPath struct {
+	path *LexemePath
+	ac   *AnalyzeContext
+}
+
+/**
+ * 路径中的全部词元，按起始位置升序、长度降序排列
+ */
+func (p *CrossPath) Lexemes() []*Lexeme {
+	return p.path.lexemes()
+}
+
+/**
+ * 路径在全文中的起始位置
+ */
+func (p *CrossPath) Begin() int {
+	return p.ac.bufOffset + p.path.pathBegin
+}
+
+/**
+ * 路径在全文中的结束位置
+ */
+func (p *CrossPath) End() int {
+	return p.ac.bufOffset + p.path.pathEnd
+}
+
+/**
+ * 路径覆盖的文本
+ */
+func (p *CrossPath) Text() string {
+	return string(p.ac.segmentBuff[p.path.pathBegin:p.path.pathEnd])
+}
+
+/**
+ * 主词典的词频之和，未给出词频的词按WORD_FREQ_DEFAULT计
+ */
+func (p *CrossPath) TotalFreq() int64 {
+	return p.ac.dicts.totalFreq
+}
+
+/**
+ * 词元的文本，歧义处理时词元尚未输出，GetText还没有内容
+ */
+func (p *CrossPath) LexemeText(l *Lexeme) string {
+	return string(p.ac.segmentBuff[l.begin : l.begin+l.length])
+}
+
+/**
+ * 对交叉路径执行歧义处理，校验结果
+ * @return 不合法的结果返回nil
+ */
+func arbitratePath(a Arbitrator, crossPath *LexemePath, context *AnalyzeContext) *LexemePath {
+	path := &CrossPath{path: crossPath, ac: context}
+	candidates := make(map[*Lexeme]bool, crossPath.set.size)
+	for c := crossPath.set.head; c != nil; c = c.next {
+		candidates[c.lexeme] = true
+	}
+
+	result := NewLexemePath()
+	for _, l := range a.Arbitrate(path) {
+		if !candidates[l] || !result.addNotCrossLexeme(l) {
+			return nil
+		}
+	}
+	return result
+}
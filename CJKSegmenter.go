@@ -26,6 +26,8 @@ func (s *CJKSegmenter) analyze(context *AnalyzeContext) {
 				if hit.isMatch() {
 					//输出当前的词
 					newLexeme := NewLexeme(context.bufOffset, hit.beg, context.cursor-hit.beg+1, LEXEME_TYPE_CNWORD)
+					newLexeme.freq = hit.freq
+					newLexeme.tag = hit.tag
 					context.addLexeme(newLexeme)
 
 					if !hit.isPrefix() { //不是词前缀，hit不需要继续匹配，移除
@@ -40,10 +42,12 @@ func (s *CJKSegmenter) analyze(context *AnalyzeContext) {
 		}
 		//*********************************
 		//再对当前指针位置的字符进行单字匹配
-		singleCharHit := MainDict.matchSeg(context.segmentBuff, context.cursor, 1)
+		singleCharHit := context.dicts.trie(DICT_TYPE_MAIN).matchSeg(context.segmentBuff, context.cursor, 1)
 		if singleCharHit.isMatch() { //首字成词
 			//输出当前的词
 			newLexeme := NewLexeme(context.bufOffset, context.cursor, 1, LEXEME_TYPE_CNWORD)
+			newLexeme.freq = singleCharHit.freq
+			newLexeme.tag = singleCharHit.tag
 			context.addLexeme(newLexeme)
 			//同时也是词前缀
 			if singleCharHit.isPrefix() {
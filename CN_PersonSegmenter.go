@@ -0,0 +1,226 @@
+package ikgo
+
+import "sync"
+
+var (
+	//手工整理的人名常用字，没有频率；只在主词典中没有标注为nr的人名时使用
+	Given_Name_Chars = []rune("伟芳娜秀英敏静丽强磊军洋勇艳杰娟涛明超兰霞平刚桂玉萍红娥玲芬燕彬鹏辉斌宇浩凯健俊帆帅旭宁龙林波飞鑫瑞琳晶欣佳丹慧颖雪婷倩琴云莉梅凤洁翠建国家文武志忠德宝海峰春秋冬夏雨晨阳光亮新福禄寿喜庆安康乐永长成发财贵荣昌盛兴旺东南中华山川江河湖金银铁木天日月星辰思怡婉淑贤惠娴雅琪瑶璐璇瑾瑜珊珍珠琦璟博远航宏鸿彦昊晖晓曦睿哲轩逸涵萱梓子诗妍语嫣若溪可馨悦紫婧媛嘉怀修铭霖泽润清源涌澄洪淼森松柏桦楠枫柳菊莲荷芝芸蓉蕾薇茜莹萌蒙梦琼瑛璋琛玮珏琰瑄立正义仁礼智信孝廉勤俭良善美真纯贞雯霏露霜岚峻崇岩峥嵘巍卫兵继承启祥麟凰鹤鹰鸣耀煜炜炎焕烨灿熙然照坤乾元亨利凡舒扬振兆宪章君卿如意望祖宗彤丰硕维纲旗凌羽翔翼翰墨书画棋剑锋锐钢钧锦绣纹绮缘恒恬情慈惟愉斐斯晴暄景朗朝")
+	GivenNameChars   map[rune]bool
+
+	//紧跟在姓氏后的称谓，不作为名字
+	Name_Titles = []string{"先生", "女士", "小姐", "老师", "师傅", "同学", "医生", "律师", "教授", "经理", "老板", "总", "主任", "书记", "局长", "部长", "阿姨", "大爷", "某"}
+)
+
+/**
+ * 名字用字的最低概率，低于该概率的字不作为名字
+ */
+const GIVEN_NAME_CHAR_MIN_PROB = 0.0005
+
+/**
+ * 人名子分词器
+ * 由姓氏词典中的姓氏加一到两个名字用字组成候选人名，输出LEXEME_TYPE_PERSON词元参与歧义处理
+ * 名字用字的概率由主词典中标注为nr的人名按词频统计，主词典中没有这样的人名时（如IK词典）改用Given_Name_Chars
+ * 候选人名在当前指针处结束，向前回看姓氏；可能构成人名的姓氏尚在处理中时锁定缓冲区
+ */
+type CN_PersonSegmenter struct {
+	name string
+}
+
+func initPS() {
+	GivenNameChars = make(map[rune]bool)
+	for _, c := range Given_Name_Chars {
+		GivenNameChars[c] = true
+	}
+}
+
+func NewCN_PersonSegmenter() *CN_PersonSegmenter {
+	return &CN_PersonSegmenter{name: "PERSON_SEGMENTER"}
+}
+
+/**
+ * 判断是否为名字用字
+ */
+func (s *CN_PersonSegmenter) isGivenNameChar(context *AnalyzeContext, index int) bool {
+	if CHAR_CHINESE != context.charType[index] {
+		return false
+	}
+	c := context.segmentBuff[index]
+	probs := context.dicts.givenNameProbs()
+	if len(probs) == 0 {
+		return GivenNameChars[c]
+	}
+	return probs[c] >= GIVEN_NAME_CHAR_MIN_PROB
+}
+
+/**
+ * 名字用字的概率，每个词典快照在第一次使用时统计一次
+ */
+type lazyGivenNameProbs struct {
+	once  sync.Once
+	probs map[rune]float64
+}
+
+func (set *dictSet) givenNameProbs() map[rune]float64 {
+	set.givenNames.once.Do(func() {
+		set.givenNames.probs = countGivenNameChars(set.mainDict)
+	})
+	return set.givenNames.probs
+}
+
+/**
+ * 按词频统计主词典中nr人名的名字用字
+ * 两个、三个字的人名去掉第一个字（单姓），四个字的去掉前两个字（复姓），其余部分作为名字
+ * @return 每个字作为名字用字的概率，没有nr人名时为空
+ */
+func countGivenNameChars(root *DictSegment) map[rune]float64 {
+	counts := make(map[rune]int64)
+	var total int64
+	word := []rune{}
+	var walk func(ds *DictSegment)
+	walk = func(ds *DictSegment) {
+		for _, child := range ds.sortedChildren() {
+			word = append(word, child.nodeChar)
+			if child.nodeState == 1 && child.tag == POS_TAG_PERSON && len(word) >= 2 && len(word) <= 4 {
+				given := word[1:]
+				if len(word) == 4 {
+					given = word[2:]
+				}
+				for _, c := range given {
+					counts[c] += int64(wordFreq(child.freq))
+					total += int64(wordFreq(child.freq))
+				}
+			}
+			walk(child)
+			word = word[:len(word)-1]
+		}
+	}
+	walk(root)
+
+	probs := make(map[rune]float64, len(counts))
+	for c, count := range counts {
+		probs[c] = float64(count) / float64(total)
+	}
+	return probs
+}
+
+/**
+ * 判断[begin, end)是否为姓氏
+ */
+func (s *CN_PersonSegmenter) isSurname(context *AnalyzeContext, begin, end int) bool {
+	if begin < 0 {
+		return false
+	}
+	for i := begin; i < end; i++ {
+		if CHAR_CHINESE != context.charType[i] {
+			return false
+		}
+	}
+	return context.dicts.trie(DICT_TYPE_SURNAME).matchSeg(context.segmentBuff, begin, end-begin).isMatch()
+}
+
+/**
+ * 判断姓氏之后是否紧跟称谓
+ * @param index 姓氏后第一个字的位置
+ */
+func (s *CN_PersonSegmenter) isTitle(context *AnalyzeContext, index int) bool {
+	for _, title := range Name_Titles {
+		chars := []rune(title)
+		if index+len(chars) > context.available {
+			continue
+		}
+		matched := true
+		for i, c := range chars {
+			if context.segmentBuff[index+i] != c {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * 判断名字中是否有字与其后的文字组成词典中的词，如"王先生"中的"先生"
+ * @param begin 名字起始位置
+ * @param end 名字结束位置（不含）
+ */
+func (s *CN_PersonSegmenter) crossesWord(context *AnalyzeContext, begin, end int) bool {
+	main := context.dicts.trie(DICT_TYPE_MAIN)
+	for i := begin; i < end; i++ {
+		for j := end + 1; j <= context.available && j-i <= context.exhaustCritical; j++ {
+			hit := main.matchSeg(context.segmentBuff, i, j-i)
+			if hit.isMatch() {
+				return true
+			}
+			if !hit.isPrefix() {
+				break
+			}
+		}
+	}
+	return false
+}
+
+/**
+ * 判断[begin, end)是否为主词典中的词，如"高明"，这样的候选不作为人名
+ */
+func (s *CN_PersonSegmenter) isWord(context *AnalyzeContext, begin, end int) bool {
+	return context.dicts.trie(DICT_TYPE_MAIN).matchSeg(context.segmentBuff, begin, end-begin).isMatch()
+}
+
+/**
+ * 输出在当前指针处结束的候选人名
+ */
+func (s *CN_PersonSegmenter) processName(context *AnalyzeContext) {
+	cursor := context.cursor
+	for givenLength := 1; givenLength <= 2; givenLength++ {
+		givenBegin := cursor - givenLength + 1
+		if givenBegin < 1 || !s.isGivenNameChar(context, givenBegin) {
+			return
+		}
+		if s.isTitle(context, givenBegin) || s.crossesWord(context, givenBegin, cursor+1) {
+			continue
+		}
+		for surnameLength := 1; surnameLength <= 2; surnameLength++ {
+			begin := givenBegin - surnameLength
+			if s.isSurname(context, begin, givenBegin) && !s.isWord(context, begin, cursor+1) {
+				newLexeme := NewLexeme(context.bufOffset, begin, cursor-begin+1, LEXEME_TYPE_PERSON)
+				context.addLexeme(newLexeme)
+			}
+		}
+	}
+}
+
+/**
+ * 判断是否有姓氏在当前指针或前一个字处结束，其后的名字尚未读完
+ */
+func (s *CN_PersonSegmenter) pending(context *AnalyzeContext) bool {
+	for end := context.cursor; end >= context.cursor-1 && end >= 0; end-- {
+		for surnameLength := 1; surnameLength <= 2; surnameLength++ {
+			if s.isSurname(context, end-surnameLength+1, end+1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *CN_PersonSegmenter) analyze(context *AnalyzeContext) {
+	if !context.usePersonNames {
+		return
+	}
+	if CHAR_CHINESE == context.charType[context.cursor] {
+		s.processName(context)
+	}
+	//判断是否锁定缓冲区
+	if !context.isBufferConsumed() && s.pending(context) {
+		context.lockBuffer(s.name)
+	} else {
+		context.unlockBuffer(s.name)
+	}
+}
+
+func (s *CN_PersonSegmenter) reset() {
+}
@@ -1,9 +1,12 @@
 package ikgo
 
-import "container/list"
+import (
+	"container/list"
+	"unicode"
+)
 
 var (
-	Chn_Num        = []rune("一二两三四五六七八九十零壹贰叁肆伍陆柒捌玖拾百千万亿拾佰仟萬億兆卅廿")
+	Chn_Num        = []rune("一二两三四五六七八九十零壹贰叁肆伍陆柒捌玖拾百千万亿拾佰仟萬億兆卅廿〇")
 	ChnNumberChars map[rune]bool
 )
 
@@ -20,9 +23,22 @@ type CN_QuantifierSegmenter struct {
 	 * end记录的是在词元中最后一个出现的合理的数词结束
 	 */
 	nEnd      int
+	nPoint    int //数词中"点"的位置，"三点五"这样的小数才包括"点"
 	countHits *list.List
+	uStart    int //紧跟在阿拉伯数字后的英文单位的开始位置
 }
 
+/**
+ * 数词与量词的合并策略
+ */
+type QuantifierPolicy int
+
+const (
+	QUANTIFIER_POLICY_IK    QuantifierPolicy = iota // 与IK一致：智能分词合并数词与中文量词，如"3.5元"；细粒度分词分开输出
+	QUANTIFIER_POLICY_SPLIT                         // 数词与量词总是分开输出，如"3.5/元"、"5/kg"
+	QUANTIFIER_POLICY_MERGE                         // 总是输出合并的数量词，细粒度分词同时输出数词与量词，如"5kg"、"100平米"
+)
+
 func initCNQS() {
 	ChnNumberChars = make(map[rune]bool)
 	for _, num := range Chn_Num {
@@ -31,7 +47,7 @@ func initCNQS() {
 }
 
 func NewCN_QuantifierSegmenter() *CN_QuantifierSegmenter {
-	return &CN_QuantifierSegmenter{nStart: -1, nEnd: -1, countHits: list.New(), name: "QUAN_SEGMENTER"}
+	return &CN_QuantifierSegmenter{nStart: -1, nEnd: -1, nPoint: -1, countHits: list.New(), uStart: -1, name: "QUAN_SEGMENTER"}
 }
 
 /**
@@ -59,17 +75,19 @@ func (s *CN_QuantifierSegmenter) processCNumber(context *AnalyzeContext) {
 			}
 		}
 	} else { //正在处理状态
-		if CHAR_CHINESE == context.charType[context.cursor] {
-			if _, exists := ChnNumberChars[context.segmentBuff[context.cursor]]; exists {
-				//记录数词的结束位置
-				s.nEnd = context.cursor
-			}
+		if _, exists := ChnNumberChars[context.segmentBuff[context.cursor]]; exists && CHAR_CHINESE == context.charType[context.cursor] {
+			//记录数词的结束位置
+			s.nEnd = context.cursor
+		} else if context.segmentBuff[context.cursor] == '点' && s.nPoint == -1 && s.nEnd+1 == context.cursor {
+			//可能是小数点，其后是数词时才计入数词
+			s.nPoint = context.cursor
 		} else {
 			//输出数词
 			s.outputNumLexeme(context)
 			//重置头尾指针
 			s.nStart = -1
 			s.nEnd = -1
+			s.nPoint = -1
 		}
 	}
 
@@ -80,6 +98,7 @@ func (s *CN_QuantifierSegmenter) processCNumber(context *AnalyzeContext) {
 		//重置头尾指针
 		s.nStart = -1
 		s.nEnd = -1
+		s.nPoint = -1
 	}
 }
 
@@ -93,13 +112,40 @@ func (s *CN_QuantifierSegmenter) needCountScan(context *AnalyzeContext) bool {
 		return true
 	}
 	//找到一个相邻的数词
-	if context.getOrgLexemes().size != 0 {
-		l := context.getOrgLexemes().peekLast()
-		if (LEXEME_TYPE_CNUM == l.lexemeType || LEXEME_TYPE_ARABIC == l.lexemeType) && (l.begin+l.length == context.cursor) {
-			return true
+	return s.numberBefore(context, context.cursor) != -1
+}
+
+/**
+ * 查找在指定位置之前紧邻的数词
+ * @param end 数词的结束位置（不含）
+ * @return 数词的起始位置，没有时为-1
+ */
+func (s *CN_QuantifierSegmenter) numberBefore(context *AnalyzeContext, end int) int {
+	if s.nStart != -1 && s.nEnd+1 == end {
+		return s.nStart
+	}
+	for c := context.getOrgLexemes().tail; c != nil && c.lexeme.begin+c.lexeme.length >= end; c = c.prev {
+		l := c.lexeme
+		if (LEXEME_TYPE_CNUM == l.lexemeType || LEXEME_TYPE_ARABIC == l.lexemeType) && l.begin+l.length == end {
+			return l.begin
 		}
 	}
-	return false
+	return -1
+}
+
+/**
+ * 输出量词词元，QUANTIFIER_POLICY_MERGE策略下同时输出与前面的数词合并的数量词
+ * @param begin 量词起始位置
+ * @param length 量词长度
+ */
+func (s *CN_QuantifierSegmenter) outputCountLexeme(context *AnalyzeContext, begin, length int) {
+	context.addLexeme(NewLexeme(context.bufOffset, begin, length, LEXEME_TYPE_COUNT))
+	if context.quantifierPolicy != QUANTIFIER_POLICY_MERGE {
+		return
+	}
+	if numBegin := s.numberBefore(context, begin); numBegin != -1 {
+		context.addLexeme(NewLexeme(context.bufOffset, numBegin, begin+length-numBegin, LEXEME_TYPE_CQUAN))
+	}
 }
 
 /**
@@ -123,8 +169,7 @@ func (s *CN_QuantifierSegmenter) processCount(context *AnalyzeContext) {
 				hit = matchWithHit(context.segmentBuff, context.cursor, hit)
 				if hit.isMatch() {
 					//输出当前的词
-					newLexeme := NewLexeme(context.bufOffset, hit.beg, context.cursor-hit.beg+1, LEXEME_TYPE_COUNT)
-					context.addLexeme(newLexeme)
+					s.outputCountLexeme(context, hit.beg, context.cursor-hit.beg+1)
 
 					if !hit.isPrefix() { //不是词前缀，hit不需要继续匹配，移除
 						s.countHits.Remove(cur)
@@ -138,11 +183,10 @@ func (s *CN_QuantifierSegmenter) processCount(context *AnalyzeContext) {
 
 		//*********************************
 		//再对当前指针位置的字符进行单字匹配
-		singleCharHit := MainDict.matchSeg(context.segmentBuff, context.cursor, 1)
+		singleCharHit := context.dicts.trie(DICT_TYPE_QUANTIFIER).matchSeg(context.segmentBuff, context.cursor, 1)
 		if singleCharHit.isMatch() { //首字成量词词
 			//输出当前的词
-			newLexeme := NewLexeme(context.bufOffset, context.cursor, 1, LEXEME_TYPE_COUNT)
-			context.addLexeme(newLexeme)
+			s.outputCountLexeme(context, context.cursor, 1)
 			//同时也是词前缀
 			if singleCharHit.isPrefix() {
 				//前缀匹配则放入hit列表
@@ -164,6 +208,47 @@ func (s *CN_QuantifierSegmenter) processCount(context *AnalyzeContext) {
 	}
 }
 
+/**
+ * 处理紧跟在阿拉伯数字后的英文单位，如"5kg"
+ * 英文单位同样由量词词典给出，匹配时不区分大小写；QUANTIFIER_POLICY_IK策略下不处理
+ */
+func (s *CN_QuantifierSegmenter) processLatinUnit(context *AnalyzeContext) {
+	if context.quantifierPolicy == QUANTIFIER_POLICY_IK {
+		return
+	}
+	if s.uStart == -1 {
+		if CHAR_ENGLISH == context.charType[context.cursor] && s.numberBefore(context, context.cursor) != -1 {
+			s.uStart = context.cursor
+		}
+	} else if CHAR_ENGLISH != context.charType[context.cursor] {
+		s.outputLatinUnit(context, context.cursor)
+	}
+	//缓冲区已经用完，还有尚未输出的单位
+	if context.isBufferConsumed() && s.uStart != -1 {
+		s.outputLatinUnit(context, context.cursor+1)
+	}
+}
+
+/**
+ * 英文字母串[uStart, end)是量词时输出量词
+ * 字母分词器输出的"数字+单位"混合词元及单位的英文词元改为数词与量词
+ */
+func (s *CN_QuantifierSegmenter) outputLatinUnit(context *AnalyzeContext, end int) {
+	begin := s.uStart
+	s.uStart = -1
+	unit := make([]rune, end-begin)
+	for i := range unit {
+		unit[i] = unicode.ToLower(context.segmentBuff[begin+i])
+	}
+	if !context.dicts.trie(DICT_TYPE_QUANTIFIER).matchSeg(unit, 0, len(unit)).isMatch() {
+		return
+	}
+	numBegin := s.numberBefore(context, begin)
+	context.getOrgLexemes().removeLexeme(NewLexeme(context.bufOffset, numBegin, end-numBegin, LEXEME_TYPE_LETTER))
+	context.getOrgLexemes().removeLexeme(NewLexeme(context.bufOffset, begin, end-begin, LEXEME_TYPE_ENGLISH))
+	s.outputCountLexeme(context, begin, end-begin)
+}
+
 /**
  * 分词
  */
@@ -172,8 +257,10 @@ func (s *CN_QuantifierSegmenter) analyze(context *AnalyzeContext) {
 	s.processCNumber(context)
 	//处理中文量词
 	s.processCount(context)
+	//处理英文单位
+	s.processLatinUnit(context)
 	//判断是否锁定缓冲区
-	if s.nStart == -1 && s.nEnd == -1 && s.countHits.Len() == 0 {
+	if s.nStart == -1 && s.nEnd == -1 && s.countHits.Len() == 0 && s.uStart == -1 {
 		//对缓冲区解锁
 		context.unlockBuffer(s.name)
 	} else {
@@ -184,5 +271,7 @@ func (s *CN_QuantifierSegmenter) analyze(context *AnalyzeContext) {
 func (s *CN_QuantifierSegmenter) reset() {
 	s.nStart = -1
 	s.nEnd = -1
+	s.nPoint = -1
 	s.countHits = list.New()
+	s.uStart = -1
 }
@@ -0,0 +1,230 @@
+package ikgo
+
+import "container/list"
+
+var (
+	//不能出现在地名、机构名前缀中的字，遇到时前缀结束
+	Entity_Prefix_Stop_Chars = []rune("这那哪每各某该本此其我你他她它谁")
+	EntityPrefixStopChars    map[rune]bool
+
+	//后缀词典未给出词性时按机构名处理的后缀，其他未给出词性的后缀不参与识别
+	Org_Suffix_Words = []string{
+		"公司", "有限公司", "股份有限公司", "集团", "大学", "学院", "中学", "小学",
+		"银行", "医院", "研究所", "研究院", "委员会", "协会", "学会", "基金会",
+		"中心", "出版社", "报社", "电视台",
+	}
+	OrgSuffixWords map[string]bool
+)
+
+const (
+	//各类实体前缀的最大长度
+	PLACE_PREFIX_MAX_LENGTH  = 4
+	ORG_PREFIX_MAX_LENGTH    = 10
+	PERSON_PREFIX_MAX_LENGTH = 5
+)
+
+/**
+ * 地名、机构名子分词器
+ * 在后缀词典中的后缀处向前回看，由"前缀 + 后缀"组成实体词元参与歧义处理，如"朝阳区"、"百姓网络科技有限公司"
+ * 后缀的类型由后缀词典中的词性决定：ns为地名，nt为机构名，nr为音译人名后缀（如"斯基"，只在开启人名识别时使用），
+ * 未给出词性时只有Org_Suffix_Words中的后缀按机构名处理，其他词性的后缀不参与识别
+ * 前缀由词典词及未登录字组成，遇到非中文字符、停止词、介词、量词或Entity_Prefix_Stop_Chars时结束，至少两个字
+ * 词典词取自中文子分词器已输出的词元，因此应在中文子分词器之后执行
+ * 由WithPlaceOrgNames开启，音译人名由WithPersonNames开启
+ */
+type CN_SuffixSegmenter struct {
+	name string
+	hits *list.List //尚未匹配完的后缀
+}
+
+func initSS() {
+	EntityPrefixStopChars = make(map[rune]bool)
+	for _, c := range Entity_Prefix_Stop_Chars {
+		EntityPrefixStopChars[c] = true
+	}
+	OrgSuffixWords = make(map[string]bool)
+	for _, w := range Org_Suffix_Words {
+		OrgSuffixWords[w] = true
+	}
+}
+
+func NewCN_SuffixSegmenter() *CN_SuffixSegmenter {
+	return &CN_SuffixSegmenter{name: "SUFFIX_SEGMENTER", hits: list.New()}
+}
+
+/**
+ * 由后缀的词性确定实体类型及前缀最大长度
+ * @param suffix 后缀，未给出词性时查找Org_Suffix_Words
+ * @return 类型，前缀最大长度；不参与识别时长度为0
+ */
+func suffixEntityType(suffix string, tag string) (LexemeType, int) {
+	if tag == "" && OrgSuffixWords[suffix] {
+		tag = POS_TAG_ORG
+	}
+	switch tag {
+	case POS_TAG_PLACE:
+		return LEXEME_TYPE_PLACE, PLACE_PREFIX_MAX_LENGTH
+	case POS_TAG_ORG:
+		return LEXEME_TYPE_ORG, ORG_PREFIX_MAX_LENGTH
+	case POS_TAG_PERSON:
+		return LEXEME_TYPE_PERSON, PERSON_PREFIX_MAX_LENGTH
+	}
+	return LEXEME_TYPE_UNKNOWN, 0
+}
+
+/**
+ * 判断[begin, end)是否可以作为前缀的一部分
+ * 排除停止词、介词及以数字或Entity_Prefix_Stop_Chars开头的部分，单字另外排除数词及量词
+ */
+func (s *CN_SuffixSegmenter) isPrefixUnit(context *AnalyzeContext, begin, end int) bool {
+	for i := begin; i < end; i++ {
+		if CHAR_CHINESE != context.charType[i] {
+			return false
+		}
+	}
+	if context.dicts.isStopWord(context.segmentBuff, begin, end-begin) ||
+		context.dicts.trie(DICT_TYPE_PREP).matchSeg(context.segmentBuff, begin, end-begin).isMatch() {
+		return false
+	}
+	c := context.segmentBuff[begin]
+	if _, isDigit := chnDigits[c]; isDigit || EntityPrefixStopChars[c] {
+		return false
+	}
+	return end-begin > 1 || (!ChnNumberChars[c] &&
+		!context.dicts.trie(DICT_TYPE_QUANTIFIER).matchSeg(context.segmentBuff, begin, 1).isMatch())
+}
+
+/**
+ * 在已输出的词元中查找在end处结束、起始位置不小于lowest的最长词典词
+ * @return 词元的副本，没有时为nil
+ */
+func (s *CN_SuffixSegmenter) wordEndingAt(context *AnalyzeContext, end, lowest int) *Lexeme {
+	var word *Lexeme
+	for c := context.orgLexemes.tail; c != nil && c.lexeme.begin >= lowest; c = c.prev {
+		l := c.lexeme
+		isWord := l.lexemeType == LEXEME_TYPE_CNWORD || l.lexemeType == LEXEME_TYPE_COUNT
+		if isWord && l.begin+l.length == end && (word == nil || l.begin < word.begin) {
+			word = l
+		}
+	}
+	if word == nil {
+		return nil
+	}
+	part := *word
+	part.parts = nil
+	return &part
+}
+
+/**
+ * 从后缀起始位置向前收集前缀，每次取在当前位置结束的最长词典词，没有时取一个字
+ * 最长词典词超出前缀最大长度或为数量词时前缀结束，不截断词典词；词典以外的字逐字作为组成部分
+ * @param end 前缀结束位置（不含）
+ * @param maxLength 前缀最大长度
+ * @return 前缀的组成部分，由前到后
+ */
+func (s *CN_SuffixSegmenter) collectPrefix(context *AnalyzeContext, end, maxLength int) []*Lexeme {
+	parts := []*Lexeme{}
+	lowest := max(0, end-maxLength)
+	for end > lowest {
+		part := s.wordEndingAt(context, end, max(0, end-ORG_PREFIX_MAX_LENGTH))
+		if part == nil || part.length == 1 {
+			part = NewLexeme(context.bufOffset, end-1, 1, LEXEME_TYPE_CNCHAR)
+		}
+		if part.begin < lowest || part.lexemeType&LEXEME_TYPE_CQUAN != 0 || !s.isPrefixUnit(context, part.begin, end) {
+			break
+		}
+		parts = append(parts, part)
+		end = part.begin
+	}
+	//逆序为由前到后
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return parts
+}
+
+/**
+ * 输出以[suffixBegin, cursor]为后缀的实体
+ * 实体以运行时从主词典屏蔽的词结尾时不输出，如屏蔽"北京大学"后不再由"北京 + 大学"识别
+ * @param tag 后缀的词性
+ */
+func (s *CN_SuffixSegmenter) outputEntity(context *AnalyzeContext, suffixBegin int, tag string) {
+	end := context.cursor + 1
+	lexemeType, maxLength := suffixEntityType(string(context.segmentBuff[suffixBegin:end]), tag)
+	enabled := context.usePlaceOrgNames
+	if lexemeType == LEXEME_TYPE_PERSON {
+		//音译人名随人名识别一起开关
+		enabled = context.usePersonNames
+	}
+	if maxLength == 0 || !enabled {
+		return
+	}
+	parts := s.collectPrefix(context, suffixBegin, maxLength)
+	if len(parts) == 0 || suffixBegin-parts[0].begin < 2 {
+		return
+	}
+	suffix := s.wordEndingAt(context, end, suffixBegin)
+	if suffix == nil || suffix.begin != suffixBegin {
+		//主词典中没有的后缀
+		suffix = NewLexeme(context.bufOffset, suffixBegin, end-suffixBegin, LEXEME_TYPE_CNWORD)
+		if suffix.length == 1 {
+			suffix.lexemeType = LEXEME_TYPE_CNCHAR
+		}
+	}
+	for _, part := range parts {
+		if context.dicts.isDisabledWord(context.segmentBuff, part.begin, end-part.begin) {
+			//不恢复已从主词典屏蔽的词
+			return
+		}
+	}
+	newLexeme := NewLexeme(context.bufOffset, parts[0].begin, end-parts[0].begin, lexemeType)
+	newLexeme.parts = append(parts, suffix)
+	context.addLexeme(newLexeme)
+}
+
+/**
+ * 匹配在当前指针处结束的后缀
+ * 长后缀先输出，起止位置相同时保留长后缀的组成部分，如"有限公司"优先于"公司"
+ */
+func (s *CN_SuffixSegmenter) analyze(context *AnalyzeContext) {
+	if !context.usePlaceOrgNames && !context.usePersonNames {
+		return
+	}
+	if CHAR_CHINESE == context.charType[context.cursor] {
+		//hits按起始位置排列，先处理的是长后缀
+		for iter := s.hits.Front(); iter != nil; {
+			cur := iter
+			iter = iter.Next()
+			hit := matchWithHit(context.segmentBuff, context.cursor, cur.Value.(*Hit))
+			if hit.isMatch() {
+				s.outputEntity(context, hit.beg, hit.tag)
+			}
+			if !hit.isPrefix() {
+				s.hits.Remove(cur)
+			}
+		}
+		hit := context.dicts.trie(DICT_TYPE_SUFFIX).matchSeg(context.segmentBuff, context.cursor, 1)
+		if hit.isMatch() {
+			s.outputEntity(context, context.cursor, hit.tag)
+		}
+		if hit.isPrefix() {
+			s.hits.PushBack(hit)
+		}
+	} else {
+		s.hits.Init()
+	}
+	if context.isBufferConsumed() {
+		s.hits.Init()
+	}
+
+	//判断是否锁定缓冲区
+	if s.hits.Len() == 0 {
+		context.unlockBuffer(s.name)
+	} else {
+		context.lockBuffer(s.name)
+	}
+}
+
+func (s *CN_SuffixSegmenter) reset() {
+	s.hits.Init()
+}
@@ -0,0 +1,639 @@
+package ikgo
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var (
+	//时间表达式中除数字外可能出现的字
+	Time_Chars = []rune("年月日号天周星期礼拜今明昨去前后大本这上下个半刻午晚早中凌晨清傍深夜间里点时分秒钟头小末份")
+	TimeChars  map[rune]bool
+)
+
+const (
+	TIME_MAX_LENGTH = 32 // 待识别的连续时间用字的最大长度
+
+	//只有年份的日期，如"2017年"，年份须在此范围内
+	TIME_YEAR_MIN = 1900
+	TIME_YEAR_MAX = 2100
+)
+
+// 相对参照时间的日期，period为附带的时段
+var relativeDayWords = []struct {
+	word   string
+	days   int
+	period string
+}{
+	{"大后天", 3, ""}, {"大前天", -3, ""},
+	{"今天", 0, ""}, {"今日", 0, ""}, {"明天", 1, ""}, {"明日", 1, ""}, {"后天", 2, ""},
+	{"昨天", -1, ""}, {"昨日", -1, ""}, {"前天", -2, ""},
+	{"今晚", 0, "EV"}, {"明晚", 1, "EV"}, {"昨晚", -1, "EV"}, {"今早", 0, "MO"}, {"明早", 1, "MO"},
+}
+
+var relativeYearWords = []struct {
+	word  string
+	years int
+}{
+	{"今年", 0}, {"明年", 1}, {"后年", 2}, {"去年", -1}, {"前年", -2},
+}
+
+var relativeMonthWords = []struct {
+	word   string
+	months int
+}{
+	{"这个月", 0}, {"下个月", 1}, {"上个月", -1}, {"本月", 0}, {"下月", 1}, {"上月", -1},
+}
+
+var weekPrefixes = map[rune]int{'上': -1, '下': 1, '这': 0, '本': 0}
+
+var weekWords = []string{"星期", "礼拜", "周"}
+
+var weekdayChars = map[rune]int{
+	'一': 1, '二': 2, '三': 3, '四': 4, '五': 5, '六': 6, '日': 7, '天': 7,
+	'1': 1, '2': 2, '3': 3, '4': 4, '5': 5, '6': 6, '7': 7,
+}
+
+/*
+ * 时段，值采用TIMEX3的记法
+ * 时段内[pmFrom, pmTo)点之间的钟点按下午计，如"晚上八点"为20:00
+ * midnight为true时12点是午夜，即次日0点，如"晚上12点"
+ */
+var periodWords = []struct {
+	word         string
+	period       string
+	pmFrom, pmTo int
+	midnight     bool
+}{
+	{"凌晨", "NI", 0, 0, false}, {"半夜", "NI", 6, 12, true}, {"深夜", "NI", 6, 12, true}, {"夜里", "NI", 6, 12, true}, {"夜间", "NI", 6, 12, true},
+	{"早上", "MO", 0, 0, false}, {"早晨", "MO", 0, 0, false}, {"清晨", "MO", 0, 0, false}, {"上午", "MO", 0, 0, false},
+	{"中午", "MI", 1, 6, false}, {"下午", "AF", 1, 12, false},
+	{"傍晚", "EV", 1, 12, true}, {"晚上", "EV", 1, 12, true}, {"晚间", "EV", 1, 12, true},
+}
+
+// 时长单位，time为true时是时分秒
+var durationUnits = []struct {
+	word  string
+	unit  string
+	time  bool
+	count bool //可以带"个"
+}{
+	{"年", "Y", false, false},
+	{"月", "M", false, true},
+	{"星期", "W", false, true}, {"礼拜", "W", false, true}, {"周", "W", false, false},
+	{"天", "D", false, false}, {"日", "D", false, false},
+	{"小时", "H", true, true}, {"钟头", "H", true, true},
+	{"分钟", "M", true, false},
+	{"秒钟", "S", true, false}, {"秒", "S", true, false},
+}
+
+/**
+ * 时间子分词器
+ * 识别"2017年8月8日"、"下午三点半"、"周五晚上"、"3天"等日期、时刻及时长，输出DATE、TIME、DURATION词元
+ * 词元的规范化取值采用ISO 8601，周末为周六至周日的日期区间；相对日期按参照时间计算
+ * 时段不属于ISO 8601，按TIMEX3记为MO、MI、AF、EV、NI，另外记在词元的时段中
+ * 连续的数字及时间用字在结束后一并识别，处理期间锁定缓冲区
+ */
+type CN_TimeSegmenter struct {
+	name  string
+	start int //连续时间用字的开始位置
+}
+
+func initTS() {
+	TimeChars = make(map[rune]bool)
+	for _, c := range Time_Chars {
+		TimeChars[c] = true
+	}
+}
+
+func NewCN_TimeSegmenter() *CN_TimeSegmenter {
+	return &CN_TimeSegmenter{name: "TIME_SEGMENTER", start: -1}
+}
+
+/**
+ * 判断字符能否出现在时间表达式中
+ */
+func (s *CN_TimeSegmenter) isTimeChar(context *AnalyzeContext, index int) bool {
+	c := context.segmentBuff[index]
+	switch {
+	case CHAR_ARABIC == context.charType[index]:
+		return true
+	case c == ':' || c == '-' || c == '/' || c == '.':
+		//数字之间的分隔符
+		return index > 0 && CHAR_ARABIC == context.charType[index-1]
+	case CHAR_CHINESE == context.charType[index]:
+		return TimeChars[c] || ChnNumberChars[c]
+	}
+	return false
+}
+
+/**
+ * 判断时间表达式能否从该字符开始，字母或小数点后的数字不能作为开始，"第三天"等序数也不是时间
+ */
+func (s *CN_TimeSegmenter) isTimeStart(context *AnalyzeContext, index int) bool {
+	if !s.isTimeChar(context, index) || (index > 0 && s.isTimeChar(context, index-1)) {
+		return false
+	}
+	if index > 0 && context.segmentBuff[index-1] == '第' {
+		return false
+	}
+	if CHAR_ARABIC == context.charType[index] && index > 0 {
+		return CHAR_ENGLISH != context.charType[index-1] && context.segmentBuff[index-1] != '.'
+	}
+	return true
+}
+
+/**
+ * 识别[begin, end)中的时间表达式
+ */
+func (s *CN_TimeSegmenter) outputTime(context *AnalyzeContext, begin, end int) {
+	p := &timeParser{text: context.segmentBuff[begin:end], ref: context.referenceTime}
+	for i := 0; i < len(p.text); {
+		n, lexemeType, value, period := p.match(i)
+		if n == 0 {
+			//不从数字中间开始识别
+			if num := p.number(i); num.n > 0 {
+				i += num.n
+			} else {
+				i++
+			}
+			continue
+		}
+		newLexeme := NewLexeme(context.bufOffset, begin+i, n, lexemeType)
+		newLexeme.value, newLexeme.period = value, period
+		//替换相同位置的词典词、数量词等
+		context.getOrgLexemes().removeLexeme(newLexeme)
+		context.addLexeme(newLexeme)
+		i += n
+	}
+}
+
+/**
+ * 分词
+ */
+func (s *CN_TimeSegmenter) analyze(context *AnalyzeContext) {
+	if !context.useTemporal {
+		return
+	}
+	cursor := context.cursor
+	if s.start != -1 && !s.isTimeChar(context, cursor) {
+		s.outputTime(context, s.start, cursor)
+		s.start = -1
+	} else if s.start != -1 && cursor-s.start >= TIME_MAX_LENGTH {
+		s.outputTime(context, s.start, cursor)
+		s.start = cursor
+	}
+	if s.start == -1 && s.isTimeStart(context, cursor) {
+		s.start = cursor
+	}
+	//缓冲区已经用完，还有尚未识别的时间用字
+	if context.isBufferConsumed() && s.start != -1 {
+		s.outputTime(context, s.start, cursor+1)
+		s.start = -1
+	}
+	if s.start == -1 {
+		context.unlockBuffer(s.name)
+	} else {
+		context.lockBuffer(s.name)
+	}
+}
+
+func (s *CN_TimeSegmenter) reset() {
+	s.start = -1
+}
+
+/**
+ * 时间表达式解析
+ */
+type timeParser struct {
+	text []rune
+	ref  time.Time
+}
+
+// 数字及其长度，year表示可以作为年份
+type timeNumber struct {
+	value int
+	frac  string
+	n     int
+	year  bool
+}
+
+// 解析得到的日期，day为false时value已是最终取值（年、月、周）
+type timeDate struct {
+	n      int
+	date   time.Time
+	day    bool
+	value  string
+	period string
+}
+
+/**
+ * 判断text[i:]是否以word开头
+ */
+func (p *timeParser) hasWord(i int, word string) (int, bool) {
+	j := i
+	for _, c := range word {
+		if j >= len(p.text) || p.text[j] != c {
+			return 0, false
+		}
+		j++
+	}
+	return j - i, true
+}
+
+func (p *timeParser) at(i int) rune {
+	if i < len(p.text) {
+		return p.text[i]
+	}
+	return 0
+}
+
+/**
+ * 解析阿拉伯数字或中文数词
+ */
+func (p *timeParser) number(i int) (num timeNumber) {
+	j := i
+	for j < len(p.text) && p.text[j] >= '0' && p.text[j] <= '9' {
+		num.value = num.value*10 + int(p.text[j]-'0')
+		j++
+	}
+	if j > i {
+		num.n, num.year = j-i, j-i == 4
+		if num.n > 9 {
+			//超出时间表达式的范围
+			num.value = -1
+		}
+		if p.at(j) == '.' && p.at(j+1) >= '0' && p.at(j+1) <= '9' {
+			k := j + 1
+			for k < len(p.text) && p.text[k] >= '0' && p.text[k] <= '9' {
+				k++
+			}
+			num.frac, num.n = string(p.text[j+1:k]), k-i
+		}
+		return
+	}
+	for j < len(p.text) && ChnNumberChars[p.text[j]] {
+		j++
+	}
+	if j == i {
+		return
+	}
+	value, err := ParseChineseNumber(string(p.text[i:j]))
+	if err != nil {
+		return
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return
+	}
+	//"二〇一七"可以作为年份，"一千"不行
+	num.value, num.n, num.year = v, j-i, j-i == 4 && !hasUnit(p.text[i:j])
+	return
+}
+
+/**
+ * 解析text[i:]开始的数字，要求为[min, max]之间的整数，其后紧跟suffix中的一个
+ * @return 数字及包括后缀的长度
+ */
+func (p *timeParser) numberWith(i, min, max int, suffixes ...string) (int, int) {
+	num := p.number(i)
+	if num.n == 0 || num.frac != "" || num.value < min || num.value > max {
+		return 0, 0
+	}
+	for _, suffix := range suffixes {
+		if n, ok := p.hasWord(i+num.n, suffix); ok {
+			return num.value, num.n + n
+		}
+	}
+	return 0, 0
+}
+
+/**
+ * 识别text[i:]开始的最长时间表达式
+ * 只有时段没有钟点时，取值为日期或空，如"周五晚上"为"2017-08-11"
+ * @return 长度、词元类型、规范化取值及TIMEX3时段，没有时长度为0
+ */
+func (p *timeParser) match(i int) (int, LexemeType, string, string) {
+	d := p.date(i)
+	if d.n > 0 && !d.day {
+		return d.n, LEXEME_TYPE_DATE, d.value, ""
+	}
+	if d.n > 0 {
+		value := d.date.Format("2006-01-02")
+		if n, clock, period, nextDay := p.clock(i+d.n, d.period, true); n > 0 {
+			if nextDay {
+				value = d.date.AddDate(0, 0, 1).Format("2006-01-02")
+			}
+			if clock != "" {
+				value += "T" + clock
+			}
+			return d.n + n, LEXEME_TYPE_TIME, value, period
+		}
+		if d.period != "" {
+			return d.n, LEXEME_TYPE_TIME, value, d.period
+		}
+		return d.n, LEXEME_TYPE_DATE, value, ""
+	}
+	if n, clock, period, _ := p.clock(i, "", false); n > 0 {
+		value := ""
+		if clock != "" {
+			value = "T" + clock
+		}
+		return n, LEXEME_TYPE_TIME, value, period
+	}
+	if n, value := p.duration(i); n > 0 {
+		return n, LEXEME_TYPE_DURATION, value, ""
+	}
+	return 0, LEXEME_TYPE_UNKNOWN, "", ""
+}
+
+/**
+ * 解析日期
+ */
+func (p *timeParser) date(i int) (d timeDate) {
+	ref := time.Date(p.ref.Year(), p.ref.Month(), p.ref.Day(), 0, 0, 0, 0, p.ref.Location())
+	for _, w := range relativeDayWords {
+		if n, ok := p.hasWord(i, w.word); ok {
+			return timeDate{n: n, date: ref.AddDate(0, 0, w.days), day: true, period: w.period}
+		}
+	}
+	for _, w := range relativeYearWords {
+		if n, ok := p.hasWord(i, w.word); ok {
+			return p.monthDay(i, n, ref.Year()+w.years)
+		}
+	}
+	for _, w := range relativeMonthWords {
+		if n, ok := p.hasWord(i, w.word); ok {
+			month := time.Date(ref.Year(), ref.Month()+time.Month(w.months), 1, 0, 0, 0, 0, ref.Location())
+			return p.day(i, n, month.Year(), int(month.Month()))
+		}
+	}
+	if d = p.week(i, ref); d.n > 0 {
+		return
+	}
+	if num := p.number(i); num.n > 0 && num.year && num.frac == "" {
+		if n, ok := p.hasWord(i+num.n, "年"); ok {
+			//只有年份时要求在常见范围内，"等了1000年"是时长
+			if d = p.monthDay(i, num.n+n, num.value); d.n > num.n+n || (num.value >= TIME_YEAR_MIN && num.value <= TIME_YEAR_MAX) {
+				return
+			}
+			return timeDate{}
+		}
+		if sep := p.at(i + num.n); sep == '-' || sep == '/' {
+			//2017-08-08
+			j := i + num.n + 1
+			month, n := p.numberWith(j, 1, 12, string(sep))
+			if n == 0 {
+				return
+			}
+			day, m := p.numberWith(j+n, 1, 31, "")
+			if m == 0 {
+				return
+			}
+			return p.makeDay(j+n+m-i, num.value, month, day)
+		}
+		return
+	}
+	if d = p.monthDay(i, 0, ref.Year()); d.n > 0 {
+		return
+	}
+	if day, n := p.numberWith(i, 1, 31, "号"); n > 0 {
+		return p.makeDay(n, ref.Year(), int(ref.Month()), day)
+	}
+	return
+}
+
+/**
+ * 解析年份之后的月、日，text[i:i+n]为已经解析的年份
+ */
+func (p *timeParser) monthDay(i, n, year int) timeDate {
+	month, m := p.numberWith(i+n, 1, 12, "月份", "月")
+	if m == 0 {
+		if n == 0 {
+			return timeDate{}
+		}
+		return timeDate{n: n, value: fmt.Sprintf("%04d", year)}
+	}
+	return p.day(i, n+m, year, month)
+}
+
+/**
+ * 解析月份之后的日，text[i:i+n]为已经解析的年月
+ */
+func (p *timeParser) day(i, n, year, month int) timeDate {
+	day, m := p.numberWith(i+n, 1, 31, "日", "号")
+	if m == 0 {
+		return timeDate{n: n, value: fmt.Sprintf("%04d-%02d", year, month)}
+	}
+	return p.makeDay(n+m, year, month, day)
+}
+
+/**
+ * 生成日期，日期不存在时返回空
+ */
+func (p *timeParser) makeDay(n, year, month, day int) timeDate {
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, p.ref.Location())
+	if date.Day() != day {
+		return timeDate{}
+	}
+	return timeDate{n: n, date: date, day: true}
+}
+
+/**
+ * 解析星期，如"周五"、"下星期一"、"这周末"、"下周"
+ */
+func (p *timeParser) week(i int, ref time.Time) timeDate {
+	j, weeks := i, 0
+	offset, prefixed := weekPrefixes[p.at(j)]
+	if prefixed {
+		j++
+		weeks = offset
+		if p.at(j) == '个' {
+			//上个星期
+			j++
+		}
+	}
+	for _, w := range weekWords {
+		n, ok := p.hasWord(j, w)
+		if !ok {
+			continue
+		}
+		j += n
+		//参照时间所在周的周一
+		monday := ref.AddDate(0, 0, -(int(ref.Weekday())+6)%7+7*weeks)
+		if weekday, exists := weekdayChars[p.at(j)]; exists {
+			return timeDate{n: j + 1 - i, date: monday.AddDate(0, 0, weekday-1), day: true}
+		}
+		if p.at(j) == '末' {
+			//周六至周日的区间
+			return timeDate{n: j + 1 - i, value: monday.AddDate(0, 0, 5).Format("2006-01-02") + "/" + monday.AddDate(0, 0, 6).Format("2006-01-02")}
+		}
+		year, week := monday.ISOWeek()
+		if prefixed {
+			return timeDate{n: j - i, value: fmt.Sprintf("%04d-W%02d", year, week)}
+		}
+		return timeDate{}
+	}
+	return timeDate{}
+}
+
+/**
+ * 解析时段及钟点，如"下午三点半"、"8:30"、"晚上"
+ * 没有时段及日期时，钟点之后需要有"半"、"一刻"、"分"、"钟"等，如"两点之间"、"3点建议"不是时刻
+ * @param period 日期中已经带有的时段，如"今晚"
+ * @param dated 是否紧跟在日期之后
+ * @return 长度、规范化的时刻、时段，以及时刻是否在次日（"晚上12点"为次日00:00）；只有时段时时刻为空
+ */
+func (p *timeParser) clock(i int, period string, dated bool) (int, string, string, bool) {
+	j, pmFrom, pmTo, midnight := i, 0, 0, false
+	for _, w := range periodWords {
+		if n, ok := p.hasWord(i, w.word); ok {
+			j, period, pmFrom, pmTo, midnight = i+n, w.period, w.pmFrom, w.pmTo, w.midnight
+			break
+		}
+	}
+	if j == i && period != "" {
+		for _, w := range periodWords {
+			if w.period == period {
+				pmFrom, pmTo, midnight = w.pmFrom, w.pmTo, w.midnight
+				break
+			}
+		}
+	}
+	hour, minute, second := -1, 0, -1
+	num := p.number(j)
+	if n, ok := p.hasWord(j+num.n, ":"); ok && num.n > 0 && num.n <= 2 && num.frac == "" && num.value <= 24 {
+		//8:30、8:30:15
+		k := j + num.n + n
+		if m := p.number(k); m.n == 2 && m.frac == "" && m.value < 60 {
+			hour, minute, j = num.value, m.value, k+2
+			if p.at(j) == ':' {
+				if s := p.number(j + 1); s.n == 2 && s.frac == "" && s.value < 60 {
+					second, j = s.value, j+3
+				}
+			}
+		}
+	} else if h, n := p.numberWith(j, 0, 24, "点", "时"); n > 0 {
+		hourBegin := j
+		hour, j = h, j+n
+		bare := true
+		if m, ok := p.hasWord(j, "半"); ok {
+			minute, j, bare = 30, j+m, false
+		} else if q, m := p.numberWith(j, 1, 3, "刻"); m > 0 && q != 2 {
+			minute, j, bare = 15*q, j+m, false
+		} else if mm, m := p.numberWith(j, 0, 59, "分钟", "分"); m > 0 {
+			minute, j, bare = mm, j+m, false
+			if ss, m := p.numberWith(j, 0, 59, "秒钟", "秒"); m > 0 {
+				second, j = ss, j+m
+			}
+		} else if mm := p.number(j); mm.n > 0 && mm.frac == "" && mm.value < 60 && p.isMinute(j, mm.n) {
+			//三点二十、三点零五
+			minute, j, bare = mm.value, j+mm.n, false
+		} else if m, ok := p.hasWord(j, "钟"); ok {
+			j, bare = j+m, false
+		}
+		if period == "" && !dated && (bare || (num.n == 1 && p.text[hourBegin] == '一' && p.text[hourBegin+1] == '时')) {
+			//"快一点"、"两点之间"、"3点建议"、"一时半会"
+			return 0, "", "", false
+		}
+	}
+	if hour == -1 {
+		if j == i {
+			return 0, "", "", false
+		}
+		//只有时段
+		return j - i, "", period, false
+	}
+	nextDay := false
+	if hour >= pmFrom && hour < pmTo {
+		hour += 12
+	} else if hour == 12 && midnight {
+		hour, nextDay = 0, true
+	}
+	clock := fmt.Sprintf("%02d:%02d", hour, minute)
+	if second != -1 {
+		clock += fmt.Sprintf(":%02d", second)
+	}
+	return j - i, clock, period, nextDay
+}
+
+/**
+ * 判断text[i, i+n)是否为省略了"分"的分钟数，如"二十"、"零五"、"05"
+ * "三点一四"这样不带单位的中文数字串是小数，不作为分钟
+ */
+func (p *timeParser) isMinute(i, n int) bool {
+	if isArabicDigit(p.text[i]) || p.text[i] == '零' || p.text[i] == '〇' {
+		return n == 2
+	}
+	return hasUnit(p.text[i : i+n])
+}
+
+func isArabicDigit(c rune) bool {
+	return c >= '0' && c <= '9'
+}
+
+/**
+ * 解析时长，如"3天"、"一个半小时"、"两年半"
+ * @return 长度及ISO 8601时长
+ */
+func (p *timeParser) duration(i int) (int, string) {
+	j, value, counted := i, "", false
+	num := p.number(i)
+	if num.value < 0 {
+		return 0, ""
+	}
+	if num.n > 0 {
+		j, value = i+num.n, strconv.Itoa(num.value)
+		if num.frac != "" {
+			value += "." + num.frac
+		}
+		if n, ok := p.hasWord(j, "个"); ok {
+			j, counted = j+n, true
+		}
+	}
+	half := false
+	if n, ok := p.hasWord(j, "半"); ok {
+		j, half = j+n, true
+		if n, ok := p.hasWord(j, "个"); ok && num.n == 0 {
+			j, counted = j+n, true
+		}
+	}
+	if num.n == 0 && !half {
+		return 0, ""
+	}
+	for _, u := range durationUnits {
+		n, ok := p.hasWord(j, u.word)
+		if !ok || (counted && !u.count) || (u.word == "月" && !counted) {
+			continue
+		}
+		j += n
+		if !half {
+			if n, ok := p.hasWord(j, "半"); ok {
+				j, half = j+n, true
+			}
+		}
+		if half {
+			value = addHalf(value)
+		}
+		if u.time {
+			return j - i, "PT" + value + u.unit
+		}
+		return j - i, "P" + value + u.unit
+	}
+	return 0, ""
+}
+
+/**
+ * 数值加0.5，value为空时为0.5
+ */
+func addHalf(value string) string {
+	if value == "" {
+		return "0.5"
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return value
+	}
+	return strconv.FormatFloat(v+0.5, 'f', -1, 64)
+}
@@ -0,0 +1,400 @@
+package ikgo
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/**
+ * 中文数词规范化
+ * 将"一百二十三"、"两万五"、"贰佰零伍"、"廿三"、"三点一四"等中文数词转换为阿拉伯数字
+ */
+
+var (
+	ErrNumeralMalformed = errors.New("ikgo: malformed chinese numeral")
+	ErrNumeralOverflow  = errors.New("ikgo: chinese numeral overflows int64")
+)
+
+// 数字，包括大写数字
+var chnDigits = map[rune]int64{
+	'零': 0, '〇': 0,
+	'一': 1, '壹': 1,
+	'二': 2, '两': 2, '贰': 2,
+	'三': 3, '叁': 3,
+	'四': 4, '肆': 4,
+	'五': 5, '伍': 5,
+	'六': 6, '陆': 6,
+	'七': 7, '柒': 7,
+	'八': 8, '捌': 8,
+	'九': 9, '玖': 9,
+}
+
+// 节内单位
+var chnSmallUnits = map[rune]int64{
+	'十': 10, '拾': 10,
+	'百': 100, '佰': 100,
+	'千': 1000, '仟': 1000,
+}
+
+// 节单位，兆按万进制取一万亿
+var chnBigUnits = map[rune]int64{
+	'万': 1e4, '萬': 1e4,
+	'亿': 1e8, '億': 1e8,
+	'兆': 1e12,
+}
+
+// 带十位的数字，廿为二十，卅为三十
+var chnTens = map[rune]int64{
+	'廿': 20,
+	'卅': 30,
+}
+
+/**
+ * 将中文数词转换为阿拉伯数字
+ * 不含单位的数字串逐位转换并保留前导零，如"二〇〇八"为"2008"、"零零七"为"007"
+ * 含单位的按万进制计算，支持省略末位单位的口语说法，如"一万五"为"15000"、"三百五"为"350"
+ * "点"之后为小数部分，整数部分可以含单位，如"一百二十点五"为"120.5"；
+ * 小数之后可以再跟一个节单位，如"三点五万"为"35000"
+ * 整数部分超出int64范围时返回ErrNumeralOverflow，单位次序错误等无法解释的写法返回ErrNumeralMalformed
+ * @param s 中文数词
+ * @return string 规范化的阿拉伯数字
+ */
+func ParseChineseNumber(s string) (string, error) {
+	chars := []rune(s)
+	intPart, fracPart := chars, []rune(nil)
+	if i := indexRune(chars, '点'); i >= 0 {
+		intPart, fracPart = chars[:i], chars[i+1:]
+	}
+	if len(intPart) == 0 {
+		return "", ErrNumeralMalformed
+	}
+	if fracPart == nil {
+		if hasUnit(intPart) {
+			v, err := parseChnUnits(intPart)
+			if err != nil {
+				return "", err
+			}
+			return strconv.FormatInt(v, 10), nil
+		}
+		digits, err := parseChnDigits(intPart)
+		if err != nil {
+			return "", err
+		}
+		return digits, nil
+	}
+
+	//小数
+	var scale int64 = 1
+	if n := len(fracPart); n > 0 {
+		if u, exists := chnBigUnits[fracPart[n-1]]; exists {
+			scale, fracPart = u, fracPart[:n-1]
+		}
+	}
+	if len(fracPart) == 0 {
+		return "", ErrNumeralMalformed
+	}
+	var integer string
+	if hasUnit(intPart) {
+		//"一百二十点五"，整数部分含单位
+		if scale > 1 && hasBigUnit(intPart) {
+			return "", ErrNumeralMalformed
+		}
+		v, err := parseChnUnits(intPart)
+		if err != nil {
+			return "", err
+		}
+		integer = strconv.FormatInt(v, 10)
+	} else {
+		digits, err := parseChnDigits(intPart)
+		if err != nil {
+			return "", err
+		}
+		integer = digits
+	}
+	fraction, err := parseChnDigits(fracPart)
+	if err != nil && err != ErrNumeralOverflow {
+		return "", err
+	}
+	return scaleDecimal(integer, fraction, scale)
+}
+
+/**
+ * 小数乘以10的整数次幂，如"3.5"乘以节单位"万"为"35000"
+ * @param integer 整数部分的数字串
+ * @param fraction 小数部分的数字串，可以为空
+ * @param scale 10的整数次幂
+ */
+func scaleDecimal(integer, fraction string, scale int64) (string, error) {
+	for ; scale > 1; scale /= 10 {
+		//乘以节单位，小数点右移
+		if len(fraction) > 0 {
+			integer, fraction = integer+fraction[:1], fraction[1:]
+		} else {
+			integer += "0"
+		}
+	}
+	integer = strings.TrimLeft(integer, "0")
+	if integer == "" {
+		integer = "0"
+	}
+	if _, err := strconv.ParseInt(integer, 10, 64); err != nil {
+		return "", ErrNumeralOverflow
+	}
+	fraction = strings.TrimRight(fraction, "0")
+	if fraction == "" {
+		return integer, nil
+	}
+	return integer + "." + fraction, nil
+}
+
+/**
+ * 逐位转换不含单位的数字串
+ */
+func parseChnDigits(chars []rune) (string, error) {
+	var b strings.Builder
+	for _, c := range chars {
+		d, exists := chnDigits[c]
+		if !exists {
+			return "", ErrNumeralMalformed
+		}
+		b.WriteByte(byte('0' + d))
+	}
+	digits := b.String()
+	if _, err := strconv.ParseInt(digits, 10, 64); err != nil {
+		return digits, ErrNumeralOverflow
+	}
+	return digits, nil
+}
+
+/**
+ * 按万进制计算含单位的数词
+ */
+func parseChnUnits(chars []rune) (int64, error) {
+	var (
+		total      int64 //已完成的节
+		section    int64 //当前节中已经带单位的部分
+		digit      int64 = -1
+		lastSmall  int64 //当前节中最后一个节内单位
+		maxBig     int64 //出现过的最大节单位
+		lastBig    int64 //最后一个节单位
+		lastUnit   int64 //紧邻当前数字之前的单位，用于"一万五"这样省略末位单位的说法
+		zero       bool  //当前数字之前是否有"零"
+		seenDigits bool
+		ok         bool
+	)
+	for _, c := range chars {
+		if d, exists := chnDigits[c]; exists {
+			if digit >= 0 {
+				//两个数字之间没有单位
+				return 0, ErrNumeralMalformed
+			}
+			if d == 0 {
+				zero, lastUnit = true, 0
+				continue
+			}
+			digit, seenDigits = d, true
+			continue
+		}
+		if t, exists := chnTens[c]; exists {
+			if digit >= 0 || (lastSmall != 0 && lastSmall <= 10) {
+				return 0, ErrNumeralMalformed
+			}
+			section += t
+			lastSmall, lastUnit, zero, seenDigits = 10, 0, false, true
+			continue
+		}
+		if u, exists := chnSmallUnits[c]; exists {
+			if lastSmall != 0 && u >= lastSmall {
+				return 0, ErrNumeralMalformed
+			}
+			if digit < 0 {
+				//"十五"、"百万"省略了一
+				digit = 1
+			}
+			section += digit * u
+			digit, lastSmall, lastUnit, zero, seenDigits = -1, u, u, false, true
+			continue
+		}
+		if u, exists := chnBigUnits[c]; exists {
+			v := section
+			if digit >= 0 {
+				v += digit
+			}
+			if v == 0 && !seenDigits {
+				//"万"、"亿"单独出现
+				v = 1
+			}
+			switch {
+			case u > maxBig:
+				//"三万亿"，节单位逐级放大
+				if total, ok = addInt64(total, v); !ok {
+					return 0, ErrNumeralOverflow
+				}
+				if total, ok = mulInt64(total, u); !ok {
+					return 0, ErrNumeralOverflow
+				}
+				maxBig = u
+			case v > 0 && u < lastBig:
+				if v, ok = mulInt64(v, u); !ok {
+					return 0, ErrNumeralOverflow
+				}
+				if total, ok = addInt64(total, v); !ok {
+					return 0, ErrNumeralOverflow
+				}
+			default:
+				return 0, ErrNumeralMalformed
+			}
+			section, digit, lastSmall, lastBig, lastUnit, zero, seenDigits = 0, -1, 0, u, u, false, true
+			continue
+		}
+		return 0, ErrNumeralMalformed
+	}
+	if digit >= 0 {
+		if !zero && lastUnit >= 100 {
+			//末位单位省略
+			section += digit * (lastUnit / 10)
+		} else {
+			section += digit
+		}
+	}
+	total, ok = addInt64(total, section)
+	if !ok {
+		return 0, ErrNumeralOverflow
+	}
+	return total, nil
+}
+
+func hasUnit(chars []rune) bool {
+	for _, c := range chars {
+		if _, exists := chnDigits[c]; !exists {
+			return true
+		}
+	}
+	return false
+}
+
+func hasBigUnit(chars []rune) bool {
+	for _, c := range chars {
+		if _, exists := chnBigUnits[c]; exists {
+			return true
+		}
+	}
+	return false
+}
+
+func indexRune(chars []rune, r rune) int {
+	for i, c := range chars {
+		if c == r {
+			return i
+		}
+	}
+	return -1
+}
+
+func addInt64(a, b int64) (int64, bool) {
+	if a > math.MaxInt64-b {
+		return 0, false
+	}
+	return a + b, true
+}
+
+func mulInt64(a, b int64) (int64, bool) {
+	if a != 0 && b > math.MaxInt64/a {
+		return 0, false
+	}
+	return a * b, true
+}
+
+/**
+ * 转换阿拉伯数字后跟中文单位的数词，如"5千"为"5000"、"3.5万"为"35000"、"5千万"为"50000000"
+ * @param arabic 阿拉伯数字，可以带小数
+ * @param units 中文单位，不含数字
+ */
+func parseArabicUnits(arabic string, units []rune) (string, error) {
+	if len(units) == 0 || !hasUnit(units) {
+		return "", ErrNumeralMalformed
+	}
+	//以"一"补足数字，由parseChnUnits校验单位次序，如"千万"为"一千万"
+	scale, err := parseChnUnits(append([]rune{'一'}, units...))
+	if err != nil {
+		return "", err
+	}
+	integer, fraction := arabic, ""
+	if i := strings.IndexByte(arabic, '.'); i >= 0 {
+		integer, fraction = arabic[:i], arabic[i+1:]
+	}
+	return scaleDecimal(integer, fraction, scale)
+}
+
+/**
+ * 为中文数词及以中文数词开头的数量词设置规范化取值
+ * 数量词的取值只包括数词部分，如"三个"为"3"；无法转换的数词不设置取值
+ * 以阿拉伯数字开头的数词只在其后跟中文单位时设置取值，如"5千元"为"5000"
+ * @param l
+ */
+func (ac *AnalyzeContext) normalizeNumeral(l *Lexeme) {
+	if l.lexemeType != LEXEME_TYPE_CNUM && l.lexemeType != LEXEME_TYPE_CQUAN {
+		return
+	}
+	if isArabicDigit(ac.segmentBuff[l.begin]) {
+		ac.normalizeArabicNumeral(l)
+		return
+	}
+	n, point := 0, false
+	for n < l.length {
+		c := ac.segmentBuff[l.begin+n]
+		if c == '点' && !point && n > 0 && n+1 < l.length && ChnNumberChars[ac.segmentBuff[l.begin+n+1]] {
+			//小数点
+			point = true
+		} else if !ChnNumberChars[c] {
+			break
+		}
+		n++
+	}
+	if n == 0 {
+		return
+	}
+	value, err := ParseChineseNumber(string(ac.segmentBuff[l.begin : l.begin+n]))
+	if err != nil {
+		return
+	}
+	l.value = value
+	if ac.useNumeralFilter {
+		l.lexemeText = value + string(ac.segmentBuff[l.begin+n:l.begin+l.length])
+	}
+}
+
+/**
+ * 为阿拉伯数字后跟中文单位的数词设置规范化取值
+ */
+func (ac *AnalyzeContext) normalizeArabicNumeral(l *Lexeme) {
+	buff := ac.segmentBuff[l.begin : l.begin+l.length]
+	n, point := 0, false
+	for n < len(buff) {
+		c := buff[n]
+		if c == '.' && !point && n > 0 && n+1 < len(buff) && isArabicDigit(buff[n+1]) {
+			point = true
+		} else if !isArabicDigit(c) {
+			break
+		}
+		n++
+	}
+	m := n
+	for m < len(buff) && ChnNumberChars[buff[m]] {
+		if _, exists := chnDigits[buff[m]]; exists {
+			//"5千3"之类的写法不转换
+			return
+		}
+		m++
+	}
+	value, err := parseArabicUnits(string(buff[:n]), buff[n:m])
+	if err != nil {
+		return
+	}
+	l.value = value
+	if ac.useNumeralFilter {
+		l.lexemeText = value + string(buff[m:])
+	}
+}
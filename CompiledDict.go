@@ -0,0 +1,351 @@
+package ikgo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sort"
+)
+
+/**
+ * 编译后的二进制词典格式
+ *
+ * 文件头（24字节，小端序）：
+ *   magic    [8]byte  "IKGODICT"
+ *   version  uint32   格式版本
+ *   checksum uint32   数据区的CRC32(IEEE)
+ *   length   uint64   数据区长度
+ * 数据区：按DICT_TYPE_MAIN ... DICT_TYPE_STOP的顺序依次存放六棵词典树，
+ * 每棵树先写uvarint节点数（不含根节点）及uvarint根节点的子节点数，再按先序遍历写出每个节点：
+ *   uvarint nodeChar, byte nodeState, uvarint freq, uvarint 词性长度, 词性, uvarint 子节点数
+ * 子节点按nodeChar升序排列
+ * 六棵词典树之后写uvarint运行时从主词典屏蔽的词数，再按字典序写出每个词：uvarint长度, UTF-8文本
+ * 运行时的修改已经体现在词典树中，修改记录本身不写出，编译后的词典也不能重新加载
+ * 版本1的节点没有freq，版本2的节点没有词性，版本3没有屏蔽词，读取时仍然支持
+ */
+const (
+	COMPILED_DICT_MAGIC   = "IKGODICT"
+	COMPILED_DICT_VERSION = 4
+
+	compiledHeaderSize = 24
+	dictTypeCount      = DICT_TYPE_STOP + 1
+)
+
+var (
+	ErrCompiledDictFormat   = errors.New("ikgo: not a compiled dictionary")
+	ErrCompiledDictVersion  = errors.New("ikgo: unsupported compiled dictionary version")
+	ErrCompiledDictChecksum = errors.New("ikgo: compiled dictionary checksum mismatch")
+)
+
+/**
+ * 将当前词典快照编译为二进制格式写出
+ * @param w 输出
+ */
+func (d *Dictionary) WriteCompiled(w io.Writer) error {
+	set := d.load()
+	payload := &bytes.Buffer{}
+	for dictType := 0; dictType < dictTypeCount; dictType++ {
+		root := set.segmentOf(dictType)
+		children := root.sortedChildren()
+		writeUvarint(payload, uint64(root.countNodes()))
+		writeUvarint(payload, uint64(len(children)))
+		for _, child := range children {
+			child.writeCompiled(payload)
+		}
+	}
+	disabled := make([]string, 0, len(set.disabledWords))
+	for word := range set.disabledWords {
+		disabled = append(disabled, word)
+	}
+	sort.Strings(disabled)
+	writeUvarint(payload, uint64(len(disabled)))
+	for _, word := range disabled {
+		writeUvarint(payload, uint64(len(word)))
+		payload.WriteString(word)
+	}
+
+	header := make([]byte, compiledHeaderSize)
+	copy(header, COMPILED_DICT_MAGIC)
+	binary.LittleEndian.PutUint32(header[8:], COMPILED_DICT_VERSION)
+	binary.LittleEndian.PutUint32(header[12:], crc32.ChecksumIEEE(payload.Bytes()))
+	binary.LittleEndian.PutUint64(header[16:], uint64(payload.Len()))
+
+	bw := bufio.NewWriter(w)
+	bw.Write(header)
+	bw.Write(payload.Bytes())
+	return bw.Flush()
+}
+
+func writeUvarint(buf *bytes.Buffer, v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	buf.Write(tmp[:n])
+}
+
+/**
+ * 先序写出当前节点及其全部子节点
+ */
+func (ds *DictSegment) writeCompiled(buf *bytes.Buffer) {
+	children := ds.sortedChildren()
+	writeUvarint(buf, uint64(ds.nodeChar))
+	buf.WriteByte(byte(ds.nodeState))
+	writeUvarint(buf, uint64(ds.freq))
+	writeUvarint(buf, uint64(len(ds.tag)))
+	buf.WriteString(ds.tag)
+	writeUvarint(buf, uint64(len(children)))
+	for _, child := range children {
+		child.writeCompiled(buf)
+	}
+}
+
+/**
+ * 返回按nodeChar升序排列的子节点
+ */
+func (ds *DictSegment) sortedChildren() []*DictSegment {
+	children := make([]*DictSegment, 0, ds.storeSize)
+	if ds.childrenMap != nil {
+		for _, child := range ds.childrenMap {
+			children = append(children, child)
+		}
+		sort.Slice(children, func(i, j int) bool {
+			return children[i].nodeChar < children[j].nodeChar
+		})
+	} else {
+		for i := 0; i < ds.storeSize && i < len(ds.childrenArray); i++ {
+			children = append(children, ds.childrenArray[i])
+		}
+	}
+	return children
+}
+
+/**
+ * 统计当前节点下的节点数（不含当前节点）
+ */
+func (ds *DictSegment) countNodes() int {
+	count := 0
+	for _, child := range ds.sortedChildren() {
+		count += 1 + child.countNodes()
+	}
+	return count
+}
+
+/**
+ * 二进制词典解码器
+ */
+type compiledReader struct {
+	data         []byte
+	pos          int
+	version      uint32
+	nodes, words int
+	tags         map[string]string //同样的词性共享一个字符串
+}
+
+func (r *compiledReader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		return 0, ErrCompiledDictFormat
+	}
+	r.pos += n
+	return v, nil
+}
+
+/**
+ * 读取词性
+ */
+func (r *compiledReader) tag() (string, error) {
+	n, err := r.uvarint()
+	if err != nil {
+		return "", err
+	}
+	if n > uint64(len(r.data)-r.pos) {
+		return "", ErrCompiledDictFormat
+	}
+	if n == 0 {
+		return "", nil
+	}
+	b := r.data[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	tag, exists := r.tags[string(b)]
+	if !exists {
+		tag = string(b)
+		r.tags[tag] = tag
+	}
+	return tag, nil
+}
+
+/**
+ * 读取运行时从主词典屏蔽的词
+ */
+func (r *compiledReader) disabledWords() (map[string]bool, error) {
+	count, err := r.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if count > uint64(len(r.data)-r.pos) {
+		return nil, ErrCompiledDictFormat
+	}
+	words := make(map[string]bool, count)
+	for i := 0; i < int(count); i++ {
+		n, err := r.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(len(r.data)-r.pos) {
+			return nil, ErrCompiledDictFormat
+		}
+		words[string(r.data[r.pos:r.pos+int(n)])] = true
+		r.pos += int(n)
+	}
+	return words, nil
+}
+
+/**
+ * 读取一个节点及其全部子节点
+ */
+func (r *compiledReader) readNode() (*DictSegment, error) {
+	nodeChar, err := r.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if r.pos >= len(r.data) {
+		return nil, ErrCompiledDictFormat
+	}
+	ds := NewDictSegment(rune(nodeChar))
+	ds.nodeState = int(r.data[r.pos])
+	r.pos++
+	if r.version >= 2 {
+		freq, err := r.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		ds.freq = int(freq)
+	}
+	if r.version >= 3 {
+		if ds.tag, err = r.tag(); err != nil {
+			return nil, err
+		}
+	}
+	r.nodes++
+	if ds.nodeState == 1 {
+		r.words++
+	}
+	if err = r.readChildren(ds); err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
+
+/**
+ * 读取子节点数及全部子节点，按数量直接选择数组或Map存储
+ */
+func (r *compiledReader) readChildren(ds *DictSegment) error {
+	count, err := r.uvarint()
+	if err != nil {
+		return err
+	}
+	if count > uint64(len(r.data)-r.pos) {
+		return ErrCompiledDictFormat
+	}
+	if count == 0 {
+		return nil
+	}
+	if int(count) <= ARRAY_LENGTH_LIMIT {
+		ds.childrenArray = make([]*DictSegment, ARRAY_LENGTH_LIMIT)
+	} else {
+		ds.childrenMap = make(map[rune]*DictSegment, count)
+	}
+	for i := 0; i < int(count); i++ {
+		child, err := r.readNode()
+		if err != nil {
+			return err
+		}
+		if ds.childrenMap != nil {
+			ds.childrenMap[child.nodeChar] = child
+		} else {
+			ds.childrenArray[i] = child
+		}
+	}
+	ds.storeSize = int(count)
+	return nil
+}
+
+/**
+ * 从编译后的二进制数据构建词典
+ * 数据在返回后不再被引用
+ * @param data 二进制词典内容
+ * @return *Dictionary, error
+ */
+func ReadCompiledDictionary(data []byte, opts ...LoadOption) (*Dictionary, error) {
+	if len(data) < compiledHeaderSize || string(data[:8]) != COMPILED_DICT_MAGIC {
+		return nil, ErrCompiledDictFormat
+	}
+	version := binary.LittleEndian.Uint32(data[8:])
+	if version < 1 || version > COMPILED_DICT_VERSION {
+		return nil, fmt.Errorf("%w: %d", ErrCompiledDictVersion, version)
+	}
+	length := binary.LittleEndian.Uint64(data[16:])
+	if length != uint64(len(data)-compiledHeaderSize) {
+		return nil, ErrCompiledDictFormat
+	}
+	payload := data[compiledHeaderSize:]
+	if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(data[12:]) {
+		return nil, ErrCompiledDictChecksum
+	}
+
+	set := newDictSet()
+	r := &compiledReader{data: payload, version: version, tags: make(map[string]string)}
+	for dictType := 0; dictType < dictTypeCount; dictType++ {
+		root := set.segmentOf(dictType)
+		nodes, err := r.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		r.nodes, r.words = 0, 0
+		if err = r.readChildren(root); err != nil {
+			return nil, err
+		}
+		if uint64(r.nodes) != nodes {
+			return nil, ErrCompiledDictFormat
+		}
+		set.report.Files = append(set.report.Files, DictFileStat{Name: compiledDictNames[dictType], Words: r.words})
+	}
+	if version >= 4 {
+		disabled, err := r.disabledWords()
+		if err != nil {
+			return nil, err
+		}
+		set.disabledWords = disabled
+	}
+	if r.pos != len(payload) {
+		return nil, ErrCompiledDictFormat
+	}
+	o := &loadOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return newDictionaryWithSet(set, o), nil
+}
+
+// 加载报告中编译词典各部分的名称
+var compiledDictNames = [dictTypeCount]string{
+	PATH_DIC_MAIN, PATH_DIC_SURNAME, PATH_DIC_QUANTIFIER, PATH_DIC_SUFFIX, PATH_DIC_PREP, PATH_DIC_STOP,
+}
+
+/**
+ * 加载编译后的二进制词典文件
+ * 支持mmap的平台上以只读方式映射文件，解码完成后即解除映射
+ * @param path 文件路径
+ * @return *Dictionary, error
+ */
+func LoadCompiledDictionary(path string, opts ...LoadOption) (*Dictionary, error) {
+	data, release, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return ReadCompiledDictionary(data, opts...)
+}
@@ -0,0 +1,239 @@
+package ikgo
+
+import "fmt"
+
+/**
+ * 一次运行时的词条修改
+ */
+type wordPatch struct {
+	dictType int
+	word     []rune
+	enabled  int
+	freq     int    //词频，未给出时为0
+	tag      string //词性，未给出时为空
+}
+
+/**
+ * 修改记录按词典类型及词条压缩
+ */
+type patchKey struct {
+	dictType int
+	word     string
+}
+
+/**
+ * 将修改应用到尚未发布的词典树
+ */
+func (p wordPatch) apply(ds *DictSegment) {
+	ds.fillSegmentSeg(p.word, 0, len(p.word), p.enabled)
+	if p.enabled == 1 && (p.freq > 0 || p.tag != "") {
+		ds.setWordAttr(p.word, p.freq, p.tag)
+	}
+}
+
+/**
+ * 写时复制的词典树修改器
+ * 沿修改路径复制节点，同一批修改中已复制的节点直接修改
+ */
+type cowWriter struct {
+	owned map[*DictSegment]bool
+}
+
+func (w *cowWriter) own(ds *DictSegment) *DictSegment {
+	if w.owned[ds] {
+		return ds
+	}
+	nds := ds.clone()
+	w.owned[nds] = true
+	return nds
+}
+
+/**
+ * 向已复制的节点ds下填充词条
+ * @param p.enabled =1添加词条 ; =0屏蔽词条
+ */
+func (w *cowWriter) fill(ds *DictSegment, charArray []rune, p wordPatch) {
+	child := ds.lookforSegment(charArray[0], 0)
+	if child == nil {
+		if p.enabled == 0 {
+			//屏蔽不存在的词，无需处理
+			return
+		}
+		child = ds.lookforSegment(charArray[0], 1)
+		w.owned[child] = true
+	} else if nc := w.own(child); nc != child {
+		ds.replaceChild(nc)
+		child = nc
+	}
+
+	if len(charArray) > 1 {
+		w.fill(child, charArray[1:], p)
+		return
+	}
+	child.nodeState = p.enabled
+	if p.enabled == 1 && (p.freq > 0 || p.tag != "") {
+		child.freq, child.tag = p.freq, p.tag
+	}
+}
+
+/**
+ * 批量修改指定类型的词典，发布新的词典快照
+ * 正在分词的goroutine继续使用旧快照，不受影响
+ */
+func (d *Dictionary) patchWords(dictType int, words []string, enabled int) error {
+	patches := make([]wordPatch, 0, len(words))
+	for _, word := range words {
+		patches = append(patches, wordPatch{dictType: dictType, word: []rune(word), enabled: enabled})
+	}
+	return d.applyPatches(dictType, patches)
+}
+
+/**
+ * 以写时复制的方式应用一批修改并记录，词典重新加载后重放
+ */
+func (d *Dictionary) applyPatches(dictType int, patches []wordPatch) error {
+	d.writeLock.Lock()
+	defer d.writeLock.Unlock()
+
+	set := d.load()
+	root := set.segmentOf(dictType)
+	if root == nil {
+		return fmt.Errorf("ikgo: unknown dictionary type %d", dictType)
+	}
+
+	w := &cowWriter{owned: make(map[*DictSegment]bool)}
+	newRoot := w.own(root)
+	for _, p := range patches {
+		if len(p.word) == 0 {
+			continue
+		}
+		w.fill(newRoot, p.word, p)
+		d.recordPatch(p)
+	}
+
+	newSet := *set
+	switch dictType {
+	case DICT_TYPE_MAIN:
+		newSet.mainDict = newRoot
+	case DICT_TYPE_SURNAME:
+		newSet.surnameDict = newRoot
+	case DICT_TYPE_QUANTIFIER:
+		newSet.quantifierDict = newRoot
+	case DICT_TYPE_SUFFIX:
+		newSet.suffixDict = newRoot
+	case DICT_TYPE_PREP:
+		newSet.prepDict = newRoot
+	case DICT_TYPE_STOP:
+		newSet.stopWords = newRoot
+	}
+	if dictType == DICT_TYPE_MAIN {
+		newSet.disabledWords = updateDisabledWords(set.disabledWords, patches)
+	}
+	newSet.buildTrie(dictType, d.opts.doubleArray)
+	d.current.Store(&newSet)
+	return nil
+}
+
+/**
+ * 记录一次修改，同一词条只保留最后的状态，重新加载时的重放量不随修改次数增长
+ * 屏蔽或重新添加词条未给出词频、词性时沿用之前的，与修改词典树的结果一致
+ */
+func (d *Dictionary) recordPatch(p wordPatch) {
+	if d.patchIndex == nil {
+		d.patchIndex = make(map[patchKey]int)
+	}
+	key := patchKey{p.dictType, string(p.word)}
+	i, ok := d.patchIndex[key]
+	if !ok {
+		d.patchIndex[key] = len(d.patches)
+		d.patches = append(d.patches, p)
+		return
+	}
+	if p.enabled == 0 || (p.freq == 0 && p.tag == "") {
+		p.freq, p.tag = d.patches[i].freq, d.patches[i].tag
+	}
+	d.patches[i] = p
+}
+
+/**
+ * 由修改记录统计当前从主词典屏蔽的词，屏蔽后又重新添加的词不计入
+ */
+func disabledWords(patches []wordPatch) map[string]bool {
+	return updateDisabledWords(nil, patches)
+}
+
+/**
+ * 在已屏蔽的词上应用一批修改，返回新的集合，不修改已发布快照中的集合
+ */
+func updateDisabledWords(disabled map[string]bool, patches []wordPatch) map[string]bool {
+	words := make(map[string]bool, len(disabled))
+	for word := range disabled {
+		words[word] = true
+	}
+	for _, p := range patches {
+		if p.dictType != DICT_TYPE_MAIN {
+			continue
+		}
+		if p.enabled == 0 {
+			words[string(p.word)] = true
+		} else {
+			delete(words, string(p.word))
+		}
+	}
+	return words
+}
+
+/**
+ * 判断segmentBuff[offset, offset+length)是否为运行时从主词典屏蔽的词
+ */
+func (set *dictSet) isDisabledWord(segmentBuff []rune, offset, length int) bool {
+	return len(set.disabledWords) > 0 && set.disabledWords[string(segmentBuff[offset:offset+length])]
+}
+
+/**
+ * 向主词典添加词条，可在分词的同时调用
+ * 运行时添加的词条在词典重新加载后依然有效
+ */
+func (d *Dictionary) AddWords(words []string) {
+	d.patchWords(DICT_TYPE_MAIN, words, 1)
+}
+
+/**
+ * 从主词典屏蔽词条，可在分词的同时调用
+ */
+func (d *Dictionary) DisableWords(words []string) {
+	d.patchWords(DICT_TYPE_MAIN, words, 0)
+}
+
+/**
+ * 判断主词典是否包含词条
+ */
+func (d *Dictionary) ContainsWord(word string) bool {
+	return d.ContainsDictWord(DICT_TYPE_MAIN, word)
+}
+
+/**
+ * 向指定类型的词典添加词条，如DICT_TYPE_STOP、DICT_TYPE_QUANTIFIER
+ */
+func (d *Dictionary) AddDictWords(dictType int, words []string) error {
+	return d.patchWords(dictType, words, 1)
+}
+
+/**
+ * 从指定类型的词典屏蔽词条
+ */
+func (d *Dictionary) DisableDictWords(dictType int, words []string) error {
+	return d.patchWords(dictType, words, 0)
+}
+
+/**
+ * 判断指定类型的词典是否包含词条
+ */
+func (d *Dictionary) ContainsDictWord(dictType int, word string) bool {
+	ds := d.load().segmentOf(dictType)
+	charArray := []rune(word)
+	if ds == nil || len(charArray) == 0 {
+		return false
+	}
+	return ds.match(charArray).isMatch()
+}
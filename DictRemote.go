@@ -0,0 +1,245 @@
+package ikgo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+ * 远程词典的版本标识
+ */
+type remoteStamp struct {
+	lastModified, etag string
+}
+
+/**
+ * 远程词典最近一次成功下载的内容
+ * 词典未更新或下载失败时，重新加载沿用这份内容，词条不会从新的快照中消失
+ */
+type remoteDict struct {
+	stamp remoteStamp
+	data  []byte
+	stale bool // CheckRemote检查到已更新，下次加载时重新下载
+}
+
+var defaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+/**
+ * 指定访问远程扩展词典使用的http.Client，未指定时使用10秒超时的默认客户端
+ */
+func WithHTTPClient(c *http.Client) LoadOption {
+	return func(o *loadOptions) {
+		o.httpClient = c
+	}
+}
+
+/**
+ * 拆分配置中以分号分隔的远程词典URL，忽略空项
+ */
+func splitRemotePaths(paths string) []string {
+	urls := []string{}
+	for _, p := range strings.Split(paths, ";") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			urls = append(urls, p)
+		}
+	}
+	return urls
+}
+
+func (d *Dictionary) httpClient() *http.Client {
+	if d.opts != nil && d.opts.httpClient != nil {
+		return d.opts.httpClient
+	}
+	return defaultHTTPClient
+}
+
+/**
+ * 下载远程扩展词典及远程扩展停止词词典，合并到快照的主词典和停止词词典
+ * 已下载且未更新的词典直接使用上次的内容
+ * 远程词典为可选词典，下载失败只记录为警告，并沿用上次下载的内容
+ */
+func (d *Dictionary) loadRemoteDicts(set *dictSet) {
+	for _, url := range set.remoteExtFiles {
+		set.fillRemoteDict(d, set.mainDict, url)
+	}
+	for _, url := range set.remoteExtStopFiles {
+		set.fillRemoteDict(d, set.stopWords, url)
+	}
+}
+
+func (set *dictSet) fillRemoteDict(d *Dictionary, ds *DictSegment, url string) {
+	stat := DictFileStat{Name: url, Optional: true, Remote: true}
+
+	d.remoteLock.Lock()
+	last := d.remoteDicts[url]
+	d.remoteLock.Unlock()
+
+	var data []byte
+	if last != nil && !last.stale {
+		data = last.data
+	} else {
+		fetched, err := d.fetchRemoteDict(url)
+		if err != nil {
+			stat.Err = &DictLoadError{File: url, Optional: true, Err: err}
+			set.report.Warnings = append(set.report.Warnings, stat.Err)
+			if last != nil {
+				data = last.data
+			}
+		} else {
+			data = fetched.data
+			d.remoteLock.Lock()
+			d.remoteDicts[url] = fetched
+			d.remoteLock.Unlock()
+		}
+	}
+	if data != nil {
+		//内容已完整读入，不会出错
+		stat.Words, _ = fillFromReader(ds, bytes.NewReader(data))
+	}
+	set.report.Files = append(set.report.Files, stat)
+}
+
+/**
+ * GET远程词典，读入全部内容并记录Last-Modified/ETag
+ */
+func (d *Dictionary) fetchRemoteDict(url string) (*remoteDict, error) {
+	resp, err := d.httpClient().Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &remoteDict{
+		stamp: remoteStamp{
+			lastModified: resp.Header.Get("Last-Modified"),
+			etag:         resp.Header.Get("ETag"),
+		},
+		data: data,
+	}, nil
+}
+
+/**
+ * 以HEAD请求检查远程词典是否更新
+ * 携带上次的If-Modified-Since/If-None-Match，304或版本标识不变视为未更新
+ */
+func (d *Dictionary) remoteChanged(url string) (bool, error) {
+	d.remoteLock.Lock()
+	var last remoteStamp
+	rd, fetched := d.remoteDicts[url]
+	if fetched {
+		last = rd.stamp
+	}
+	d.remoteLock.Unlock()
+
+	req, err := http.NewRequest(http.MethodHead, url, nil)
+	if err != nil {
+		return false, err
+	}
+	if last.lastModified != "" {
+		req.Header.Set("If-Modified-Since", last.lastModified)
+	}
+	if last.etag != "" {
+		req.Header.Set("If-None-Match", last.etag)
+	}
+	resp, err := d.httpClient().Do(req)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return false, nil
+	case http.StatusOK:
+		cur := remoteStamp{
+			lastModified: resp.Header.Get("Last-Modified"),
+			etag:         resp.Header.Get("ETag"),
+		}
+		return !fetched || cur != last, nil
+	}
+	return false, fmt.Errorf("ikgo: check remote dictionary %s: unexpected status %s", url, resp.Status)
+}
+
+/**
+ * 检查一次全部远程词典，任一词典更新时重新加载词典
+ * 重新加载只下载已更新的远程词典，其余沿用上次的内容，与本地词典合并后原子替换当前快照
+ * @return changed 是否重新加载了词典
+ */
+func (d *Dictionary) CheckRemote() (changed bool, err error) {
+	set := d.load()
+	urls := append(append([]string{}, set.remoteExtFiles...), set.remoteExtStopFiles...)
+
+	errs := []error{}
+	for _, url := range urls {
+		c, e := d.remoteChanged(url)
+		if e != nil {
+			errs = append(errs, e)
+			continue
+		}
+		if c {
+			d.markRemoteStale(url)
+			changed = true
+		}
+	}
+	if changed {
+		if e := d.Reload(); e != nil {
+			errs = append(errs, e)
+		}
+	}
+	return changed, errors.Join(errs...)
+}
+
+func (d *Dictionary) markRemoteStale(url string) {
+	d.remoteLock.Lock()
+	defer d.remoteLock.Unlock()
+	if rd := d.remoteDicts[url]; rd != nil {
+		//fillRemoteDict在锁外读取remoteDict，替换而不是修改
+		d.remoteDicts[url] = &remoteDict{stamp: rd.stamp, data: rd.data, stale: true}
+	}
+}
+
+/**
+ * 定期检查远程词典，更新时重新加载词典
+ * @param interval 检查间隔，ES analysis-ik默认为60秒
+ * @param onCheck 每次检查出错或重新加载后的回调，可以为nil
+ * @return stop 停止检查的函数
+ */
+func (d *Dictionary) WatchRemote(interval time.Duration, onCheck func(changed bool, err error)) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				changed, err := d.CheckRemote()
+				if onCheck != nil && (changed || err != nil) {
+					onCheck(changed, err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+		})
+	}
+}
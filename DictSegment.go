@@ -5,16 +5,27 @@ import (
 )
 
 var (
-	charMap            map[rune]rune = make(map[rune]rune)
-	ARRAY_LENGTH_LIMIT int           = 3
+	ARRAY_LENGTH_LIMIT int = 3
 )
 
+/**
+ * 词典树节点的查询接口
+ * DictSegment与doubleArrayTrie均实现该接口，Hit通过它从已匹配的位置继续向下匹配
+ * doubleArrayTrie的当前状态记录在Hit.matchedState中
+ */
+type trieNode interface {
+	matchSeg(charArray []rune, begin, length int) *Hit
+	matchSegSearch(charArray []rune, begin, length int, searchHit *Hit) *Hit
+}
+
 type DictSegment struct {
 	childrenMap   map[rune]*DictSegment //Map存储结构
 	childrenArray []*DictSegment        //数组方式存储结构
 	nodeChar      rune                  //当前节点上存储的字符
 	storeSize     int                   //当前节点存储的Segment数目 ==> storeSize <=ARRAY_LENGTH_LIMIT ，使用数组存储， storeSize >ARRAY_LENGTH_LIMIT ,则使用Map存储
 	nodeState     int                   //当前DictSegment状态 ,默认 0 , 1表示从根节点到当前节点的路径表示一个词
+	freq          int                   //词频，词典中未给出时为0
+	tag           string                //词性，词典中未给出时为空
 }
 
 func NewDictSegment(nodeChar rune) *DictSegment {
@@ -56,11 +67,15 @@ func (ds *DictSegment) matchSegSearch(charArray []rune, begin, length int, searc
 
 	//STEP1 在节点中查找keyChar对应的DictSegment
 	if len(segmentArray) > 0 {
-		keySegment := NewDictSegment(keyChar)
-		position := sort.Search(ds.storeSize, func(i int) bool {
-			return keySegment.nodeChar == segmentArray[i].nodeChar
+		size := ds.storeSize
+		if size > len(segmentArray) {
+			size = len(segmentArray)
+		}
+		//数组按nodeChar升序排列，二分查找
+		position := sort.Search(size, func(i int) bool {
+			return segmentArray[i].nodeChar >= keyChar
 		})
-		if position != ds.storeSize {
+		if position < size && segmentArray[position].nodeChar == keyChar {
 			nds = segmentArray[position]
 		}
 	} else if len(segmentMap) > 0 {
@@ -78,12 +93,14 @@ func (ds *DictSegment) matchSegSearch(charArray []rune, begin, length int, searc
 			if nds.nodeState == 1 {
 				//添加HIT状态为完全匹配
 				searchHit.setMatch()
+				searchHit.freq = nds.freq
+				searchHit.tag = nds.tag
 			}
 			if nds.hasNextNode() {
 				//添加HIT状态为前缀匹配
 				searchHit.setPrefix()
 				//记录当前位置的DictSegment
-				searchHit.matchedDictSegment = nds
+				searchHit.matchedNode = nds
 			}
 			return searchHit
 		}
@@ -138,20 +155,19 @@ func (ds *DictSegment) lookforSegment(keyChar rune, create int) *DictSegment {
 		//获取数组容器，如果数组未创建则创建数组
 		segmentArray := ds.getChildrenArray()
 		//搜寻数组
-		keySegment := NewDictSegment(keyChar)
 		position := sort.Search(ds.storeSize, func(i int) bool {
-			return keySegment == segmentArray[i]
+			return segmentArray[i].nodeChar >= keyChar
 		})
-		if position != len(segmentArray) {
+		if position < ds.storeSize && segmentArray[position].nodeChar == keyChar {
 			nds = segmentArray[position]
 		}
 
 		//遍历数组后没有找到对应的segment
 		if nds == nil && create == 1 {
-			nds = keySegment
+			nds = NewDictSegment(keyChar)
 			if ds.storeSize < ARRAY_LENGTH_LIMIT {
 				//数组容量未满，使用数组存储，插入
-				idx := ds.storeSize
+				idx := 0
 				for i := ds.storeSize; i > 0; i-- {
 					if segmentArray[i-1].nodeChar < nds.nodeChar {
 						idx = i
@@ -205,13 +221,7 @@ func (ds *DictSegment) lookforSegment(keyChar rune, create int) *DictSegment {
  */
 func (ds *DictSegment) fillSegmentSeg(charArray []rune, begin, length, enabled int) {
 	//获取字典表中的汉字对象
-	beginChar := charArray[begin]
-	keyChar, exists := charMap[beginChar]
-	//字典中没有该字，则将其添加入字典
-	if !exists {
-		charMap[beginChar] = beginChar
-		keyChar = beginChar
-	}
+	keyChar := charArray[begin]
 
 	//搜索当前节点的存储，查询对应keyChar的keyChar，如果没有则创建
 	nds := ds.lookforSegment(keyChar, enabled)
@@ -236,3 +246,69 @@ func (ds *DictSegment) fillSegmentSeg(charArray []rune, begin, length, enabled i
 func (ds *DictSegment) fillSegment(charArray []rune) {
 	ds.fillSegmentSeg(charArray, 0, len(charArray), 1)
 }
+
+/**
+ * 设置词的词频及词性，词不在词典树中时忽略
+ * @param charArray
+ * @param freq
+ * @param tag
+ */
+func (ds *DictSegment) setWordAttr(charArray []rune, freq int, tag string) {
+	nds := ds
+	for _, c := range charArray {
+		if nds = nds.lookforSegment(c, 0); nds == nil {
+			return
+		}
+	}
+	nds.freq = freq
+	nds.tag = tag
+}
+
+/**
+ * 统计词典树中全部词的词频之和，未给出词频的词按WORD_FREQ_DEFAULT计
+ */
+func (ds *DictSegment) totalFreq() int64 {
+	var total int64
+	for _, child := range ds.sortedChildren() {
+		if child.nodeState == 1 {
+			total += int64(wordFreq(child.freq))
+		}
+		total += child.totalFreq()
+	}
+	return total
+}
+
+/**
+ * 复制当前节点，子节点容器复制一份，子节点本身共享
+ * 用于写时复制：修改复制后的节点不影响正在读取原节点的goroutine
+ */
+func (ds *DictSegment) clone() *DictSegment {
+	nds := *ds
+	if ds.childrenArray != nil {
+		nds.childrenArray = make([]*DictSegment, len(ds.childrenArray))
+		copy(nds.childrenArray, ds.childrenArray)
+	}
+	if ds.childrenMap != nil {
+		nds.childrenMap = make(map[rune]*DictSegment, len(ds.childrenMap))
+		for k, v := range ds.childrenMap {
+			nds.childrenMap[k] = v
+		}
+	}
+	return &nds
+}
+
+/**
+ * 用nodeChar相同的新节点替换已有的子节点
+ */
+func (ds *DictSegment) replaceChild(child *DictSegment) {
+	if ds.childrenMap != nil {
+		ds.childrenMap[child.nodeChar] = child
+		return
+	}
+	for i := 0; i < ds.storeSize && i < len(ds.childrenArray); i++ {
+		if ds.childrenArray[i].nodeChar == child.nodeChar {
+			ds.childrenArray[i] = child
+			return
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package ikgo
+
+import (
+	"errors"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+/**
+ * 词典文件的状态戳，用于判断文件是否发生变化
+ */
+type fileStamp struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+func (s fileStamp) equals(o fileStamp) bool {
+	return s.exists == o.exists && s.size == o.size && s.modTime.Equal(o.modTime)
+}
+
+/**
+ * 记录IKAnalyzer.cfg.xml及当前快照加载过的全部词典文件的状态戳
+ */
+func (d *Dictionary) stampFiles() map[string]fileStamp {
+	names := []string{FILE_NAME}
+	for _, f := range d.Report().Files {
+		if !f.Remote {
+			names = append(names, f.Name)
+		}
+	}
+
+	stamps := make(map[string]fileStamp, len(names))
+	for _, name := range names {
+		fi, err := fs.Stat(d.fsys, name)
+		if err != nil {
+			stamps[name] = fileStamp{}
+			continue
+		}
+		stamps[name] = fileStamp{exists: true, modTime: fi.ModTime(), size: fi.Size()}
+	}
+	return stamps
+}
+
+func stampsChanged(old, cur map[string]fileStamp) bool {
+	if len(old) != len(cur) {
+		return true
+	}
+	for name, stamp := range cur {
+		if o, exists := old[name]; !exists || !o.equals(stamp) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * 定期检查词典文件的修改时间，发生变化时重新加载词典
+ * 检查范围为IKAnalyzer.cfg.xml及其中列出的全部词典文件
+ * @param interval 检查间隔
+ * @param onReload 每次重新加载后的回调，err为nil表示加载成功，可以为nil
+ * @return stop 停止检查的函数
+ */
+func (d *Dictionary) Watch(interval time.Duration, onReload func(err error)) (stop func(), err error) {
+	if d.fsys == nil {
+		return nil, errors.New("ikgo: dictionary has no source to watch")
+	}
+
+	done := make(chan struct{})
+	stamps := d.stampFiles()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				cur := d.stampFiles()
+				if !stampsChanged(stamps, cur) {
+					continue
+				}
+				err := d.Reload()
+				//重新加载后词典文件列表可能变化，重新记录状态戳
+				stamps = d.stampFiles()
+				if onReload != nil {
+					onReload(err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			close(done)
+		})
+	}
+	return stop, nil
+}
@@ -4,18 +4,33 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"io/fs"
+	"net/http"
 	"os"
+	"path"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
 )
 
+// 包级全局词典，在InitDict或SetDefaultDictionary时指向默认词典当时的快照。
+// 默认词典之后的Reload、Watch及AddWords等运行时修改不会同步到这些变量，
+// 以免与读取它们的goroutine产生数据竞争。
+//
+// Deprecated: 使用Dictionary实例及ContainsDictWord等方法。
+var MainDict, SurnameDict, QuantifierDict, SuffixDict, PrepDict, StopWords *DictSegment
+
 var (
-	MainDict, SurnameDict, QuantifierDict, SuffixDict, PrepDict, StopWords *DictSegment
-	conf_dir                                                               string
-	ext_files, ext_stopfiles                                               []string
-	conf_smart                                                             bool
+	conf_dir                 string
+	ext_files, ext_stopfiles []string
+
+	// 默认词典实例，由InitDict或SetDefaultDictionary设置
+	defaultDict *Dictionary
 )
 
 const (
@@ -30,8 +45,18 @@ const (
 	EXT_DICT  = "ext_dict"
 	EXT_STOP  = "ext_stopwords"
 
-	//REMOTE_EXT_DICT = "remote_ext_dict"
-	//REMOTE_EXT_STOP = "remote_ext_stopwords"
+	REMOTE_EXT_DICT = "remote_ext_dict"
+	REMOTE_EXT_STOP = "remote_ext_stopwords"
+)
+
+// 词典类型
+const (
+	DICT_TYPE_MAIN = iota
+	DICT_TYPE_SURNAME
+	DICT_TYPE_QUANTIFIER
+	DICT_TYPE_SUFFIX
+	DICT_TYPE_PREP
+	DICT_TYPE_STOP
 )
 
 type DictFile struct {
@@ -43,260 +68,582 @@ type DictFiles struct {
 	DictFiles []DictFile `xml:"entry"`
 }
 
-func InitDict(dir string, bs bool) {
-	conf_dir = dir
-	conf_smart = bs
-	// 读取cfg.xml
-	content, err := ioutil.ReadFile(fmt.Sprintf("%s/%s", conf_dir, FILE_NAME))
-	if err == nil {
-		dfs := &DictFiles{}
-		err = xml.Unmarshal(content, dfs)
-		if err == nil {
-			for _, df := range dfs.DictFiles {
-				if df.Key == EXT_DICT {
-					ext_files = strings.Split(df.Path, ";")
-				} else if df.Key == EXT_STOP {
-					ext_stopfiles = strings.Split(df.Path, ";")
-				}
+/**
+ * 词典实例
+ * 持有主词典、姓氏、量词、后缀、介词、停止词六棵词典树
+ * 不同的Dictionary实例互不影响，同一进程中可以并存多套词典
+ * 词典树以整体快照的方式发布，重新加载时构建新的快照后原子替换，
+ * 正在分词的goroutine不会读到构建中的词典树
+ */
+type Dictionary struct {
+	current    atomic.Pointer[dictSet]
+	fsys       fs.FS        // 词典来源，从io.Reader构建的词典为nil
+	opts       *loadOptions // 加载选项，重新加载时沿用
+	confDir    string
+	writeLock  sync.Mutex       // 串行化重新加载及运行时的词条修改
+	patches    []wordPatch      // 运行时的词条修改，重新加载后重放，每个词条只保留最后的状态
+	patchIndex map[patchKey]int // 词条在patches中的位置
+
+	remoteLock  sync.Mutex
+	remoteDicts map[string]*remoteDict // 远程词典最近一次成功下载的内容及版本标识
+}
 
-			}
-		}
+/**
+ * 一套完整的词典树快照，发布后不再修改
+ */
+type dictSet struct {
+	mainDict, surnameDict, quantifierDict, suffixDict, prepDict, stopWords *DictSegment
+	extFiles, extStopFiles                                                 []string
+	remoteExtFiles, remoteExtStopFiles                                     []string
+	report                                                                 *LoadReport
+
+	//用于查询的词典树，默认即上面的DictSegment，也可以是由其构建的双数组Trie
+	tries [dictTypeCount]trieNode
+	//主词典的词频之和，用于计算词的概率
+	totalFreq int64
+	//由主词典训练的未登录词识别模型
+	hmm *lazyHMMModel
+	//由主词典统计的词尾字常见词性
+	tailTags *lazyTailTags
+	//由主词典中的人名统计的名字用字概率
+	givenNames *lazyGivenNameProbs
+	//运行时从主词典屏蔽的词，实体识别不应再输出这些词
+	disabledWords map[string]bool
+}
 
+func newDictSet() *dictSet {
+	set := &dictSet{
+		mainDict:       NewDictSegment(0),
+		surnameDict:    NewDictSegment(0),
+		quantifierDict: NewDictSegment(0),
+		suffixDict:     NewDictSegment(0),
+		prepDict:       NewDictSegment(0),
+		stopWords:      NewDictSegment(0),
+		report:         &LoadReport{},
 	}
-
-	loadMainDict()
-	loadSurnameDict()
-	loadQuantifierDict()
-	loadSuffixDict()
-	loadPrepDict()
-	loadStopWordDict()
+	set.buildTries(false)
+	return set
 }
 
 /**
- * 加载主词典及扩展词典
+ * 构建全部词典的查询结构
+ * @param doubleArray 是否使用双数组Trie
  */
-func loadMainDict() {
-	MainDict = NewDictSegment(0)
+func (set *dictSet) buildTries(doubleArray bool) {
+	for dictType := 0; dictType < dictTypeCount; dictType++ {
+		set.buildTrie(dictType, doubleArray)
+	}
+}
 
-	// 读取主词典文件
-	fi, err := os.Open(fmt.Sprintf("%s/%s", conf_dir, PATH_DIC_MAIN))
-	if err != nil {
-		return
+/**
+ * 构建指定词典的查询结构
+ */
+func (set *dictSet) buildTrie(dictType int, doubleArray bool) {
+	root := set.segmentOf(dictType)
+	if dictType == DICT_TYPE_MAIN {
+		set.totalFreq = root.totalFreq()
+		set.hmm = &lazyHMMModel{}
+		set.tailTags = &lazyTailTags{}
+		set.givenNames = &lazyGivenNameProbs{}
 	}
-	defer fi.Close()
+	if doubleArray {
+		set.tries[dictType] = newDoubleArrayTrie(root)
+	} else {
+		set.tries[dictType] = root
+	}
+}
 
-	br := bufio.NewReader(fi)
-	for {
-		word, _, c := br.ReadLine()
-		if c == io.EOF {
-			break
-		}
-		if word != nil {
-			trimWord := bytes.Trim(word, "\r\n\t ")
-			if !bytes.Equal(trimWord, []byte("")) {
-				MainDict.fillSegment([]rune(string(trimWord)))
-			}
+/**
+ * 返回指定词典用于查询的词典树
+ */
+func (set *dictSet) trie(dictType int) trieNode {
+	return set.tries[dictType]
+}
 
-		}
+func newDictionaryWithSet(set *dictSet, o *loadOptions) *Dictionary {
+	d := &Dictionary{opts: o, remoteDicts: make(map[string]*remoteDict)}
+	set.buildTries(o.doubleArray)
+	d.current.Store(set)
+	return d
+}
+
+func newDictionaryWithSource(fsys fs.FS, o *loadOptions) (*Dictionary, error) {
+	d := &Dictionary{fsys: fsys, opts: o, remoteDicts: make(map[string]*remoteDict)}
+	set, err := d.loadSet()
+	set.buildTries(o.doubleArray)
+	d.current.Store(set)
+	return d, err
+}
+
+/**
+ * 返回当前发布的词典树快照
+ */
+func (d *Dictionary) load() *dictSet {
+	return d.current.Load()
+}
+
+/**
+ * 单个词典文件的加载结果
+ */
+type DictFileStat struct {
+	Name     string // 相对于词典目录的文件名
+	Words    int    // 加载的词条数
+	Optional bool   // 是否为可选词典
+	Remote   bool   // 是否为远程词典，此时Name为URL
+	Err      error  // 加载失败的原因，成功时为nil
+}
+
+/**
+ * 词典加载报告
+ */
+type LoadReport struct {
+	Files    []DictFileStat
+	Warnings []error // 被降级为警告的错误
+}
+
+/**
+ * 词典加载错误
+ */
+type DictLoadError struct {
+	File     string
+	Optional bool
+	Err      error
+}
+
+func (e *DictLoadError) Error() string {
+	return fmt.Sprintf("ikgo: load dictionary %s: %v", e.File, e.Err)
+}
+
+func (e *DictLoadError) Unwrap() error {
+	return e.Err
+}
+
+type loadOptions struct {
+	missingOptionalAsWarning bool
+	httpClient               *http.Client
+	doubleArray              bool
+}
+
+type LoadOption func(*loadOptions)
+
+/**
+ * 可选词典（姓氏、后缀、介词、停止词以及扩展词典）缺失时只记录警告，不返回错误
+ * 主词典与量词词典始终是必需的
+ */
+func WithMissingOptionalAsWarning() LoadOption {
+	return func(o *loadOptions) {
+		o.missingOptionalAsWarning = true
 	}
+}
 
-	// 加载扩展词典
-	loadExtDict()
+/**
+ * 使用双数组Trie作为词典的查询结构
+ * 双数组Trie查询更快、对GC更友好，但构建较慢，运行时修改词条时需要整体重建
+ */
+func WithDoubleArrayTrie() LoadOption {
+	return func(o *loadOptions) {
+		o.doubleArray = true
+	}
 }
 
 /**
- * 加载用户配置的扩展词典到主词库表
+ * 从词典目录加载一套新的词典实例
+ * 兼容旧接口，忽略加载过程中的错误，错误可通过Report查看
+ * @param dir 词典目录，包含IKAnalyzer.cfg.xml及各词典文件
+ * @return *Dictionary
  */
-func loadExtDict() {
-	// 加载扩展词典配置
-	for _, fname := range ext_files {
-		fi, err := os.Open(fmt.Sprintf("%s/%s", conf_dir, fname))
-		if err != nil {
-			continue
-		}
-		br := bufio.NewReader(fi)
-		for {
-			word, _, c := br.ReadLine()
-			if c == io.EOF {
-				break
-			}
-			if word != nil {
-				trimWord := bytes.Trim(word, "\r\n\t ")
-				if !bytes.Equal(trimWord, []byte("")) {
-					MainDict.fillSegment([]rune(string(trimWord)))
-				}
+func NewDictionary(dir string) *Dictionary {
+	d, _ := newDictionaryWithSource(os.DirFS(dir), &loadOptions{})
+	d.confDir = dir
+	return d
+}
 
-			}
-		}
-		fi.Close()
+/**
+ * 创建一套空词典，可通过LoadWords从io.Reader逐个填充
+ * @return *Dictionary
+ */
+func NewEmptyDictionary() *Dictionary {
+	return newDictionaryWithSet(newDictSet(), &loadOptions{})
+}
+
+/**
+ * 从词典目录加载一套新的词典实例
+ * 必需词典缺失、配置文件格式错误、扩展词典无法读取时返回错误
+ * @param dir 词典目录，包含IKAnalyzer.cfg.xml及各词典文件
+ * @return *Dictionary, error
+ */
+func LoadDictionary(dir string, opts ...LoadOption) (*Dictionary, error) {
+	d, err := LoadDictionaryFS(os.DirFS(dir), opts...)
+	if err != nil {
+		return nil, err
 	}
+	d.confDir = dir
+	return d, nil
 }
 
 /**
- * 加载用户扩展的停止词词典
+ * 从文件系统加载一套新的词典实例，词典文件位于fsys的根目录
+ * 可以使用embed.FS、zip.Reader、fstest.MapFS等实现，子目录可用fs.Sub截取
+ * @param fsys 词典文件系统
+ * @return *Dictionary, error
  */
-func loadStopWordDict() {
-	StopWords = NewDictSegment(0)
+func LoadDictionaryFS(fsys fs.FS, opts ...LoadOption) (*Dictionary, error) {
+	o := &loadOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	d, err := newDictionaryWithSource(fsys, o)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+/**
+ * 从词典来源重新加载全部词典，加载成功后原子替换当前词典树
+ * 加载失败时保留原有词典树并返回错误
+ * 远程词典沿用上次下载的内容，只重新下载CheckRemote检查到已更新的词典
+ */
+func (d *Dictionary) Reload() error {
+	if d.fsys == nil {
+		return errors.New("ikgo: dictionary has no source to reload from")
+	}
+	d.writeLock.Lock()
+	defer d.writeLock.Unlock()
 
-	// 读取主词典文件
-	fi, err := os.Open(fmt.Sprintf("%s/%s", conf_dir, PATH_DIC_STOP))
+	set, err := d.loadSet()
 	if err != nil {
-		return
+		return err
 	}
-	defer fi.Close()
+	//新快照尚未发布，可以直接修改
+	for _, p := range d.patches {
+		p.apply(set.segmentOf(p.dictType))
+	}
+	set.disabledWords = disabledWords(d.patches)
+	set.buildTries(d.opts.doubleArray)
+	d.current.Store(set)
+	return nil
+}
 
-	br := bufio.NewReader(fi)
-	for {
-		word, _, c := br.ReadLine()
-		if c == io.EOF {
-			break
-		}
-		if word != nil {
-			trimWord := bytes.Trim(word, "\r\n\t ")
-			if !bytes.Equal(trimWord, []byte("")) {
-				StopWords.fillSegment([]rune(string(trimWord)))
-			}
+/**
+ * 从词典来源加载本地及远程词典，总是返回词典树快照，同时返回汇总的错误
+ * 远程词典加载失败只记录为警告
+ */
+func (d *Dictionary) loadSet() (*dictSet, error) {
+	set, err := loadDictSet(d.fsys, d.opts)
+	d.loadRemoteDicts(set)
+	return set, err
+}
 
+/**
+ * 加载全部本地词典，总是返回词典树快照，同时返回汇总的错误
+ */
+func loadDictSet(fsys fs.FS, o *loadOptions) (*dictSet, error) {
+	set := newDictSet()
+	errs := []error{}
+	addErr := func(err error) {
+		if err == nil {
+			return
+		}
+		var le *DictLoadError
+		if o.missingOptionalAsWarning && errors.As(err, &le) && le.Optional && errors.Is(err, fs.ErrNotExist) {
+			set.report.Warnings = append(set.report.Warnings, err)
+			return
 		}
+		errs = append(errs, err)
 	}
 
-	// 加载扩展词典
-	// 加载扩展词典配置
-	for _, fname := range ext_stopfiles {
-		fi, err := os.Open(fmt.Sprintf("%s/%s", conf_dir, fname))
-		if err != nil {
-			continue
-		}
-		br := bufio.NewReader(fi)
-		for {
-			word, _, c := br.ReadLine()
-			if c == io.EOF {
-				break
-			}
-			if word != nil {
-				trimWord := bytes.Trim(word, "\r\n\t ")
-				if !bytes.Equal(trimWord, []byte("")) {
-					MainDict.fillSegment([]rune(string(trimWord)))
-				}
+	addErr(set.loadConfig(fsys))
 
-			}
-		}
-		fi.Close()
+	addErr(set.fillDictFile(fsys, set.mainDict, PATH_DIC_MAIN, false))
+	for _, fname := range set.extFiles {
+		addErr(set.fillDictFile(fsys, set.mainDict, fname, true))
 	}
+	addErr(set.fillDictFile(fsys, set.quantifierDict, PATH_DIC_QUANTIFIER, false))
+	addErr(set.fillDictFile(fsys, set.surnameDict, PATH_DIC_SURNAME, true))
+	addErr(set.fillDictFile(fsys, set.suffixDict, PATH_DIC_SUFFIX, true))
+	addErr(set.fillDictFile(fsys, set.prepDict, PATH_DIC_PREP, true))
+	addErr(set.fillDictFile(fsys, set.stopWords, PATH_DIC_STOP, true))
+	for _, fname := range set.extStopFiles {
+		addErr(set.fillDictFile(fsys, set.stopWords, fname, true))
+	}
+
+	return set, errors.Join(errs...)
 }
 
 /**
- * 加载量词词典
+ * 初始化默认词典
+ * 兼容旧接口，加载结果同时写入包级全局词典；bs不再使用，分词模式由WithSmart指定
  */
-func loadQuantifierDict() {
-	// 建立一个量词典实例
-	QuantifierDict = NewDictSegment(0)
+func InitDict(dir string, bs bool) {
+	SetDefaultDictionary(NewDictionary(dir))
+}
 
-	// 读取主词典文件
-	fi, err := os.Open(fmt.Sprintf("%s/%s", conf_dir, PATH_DIC_QUANTIFIER))
-	if err != nil {
-		return
-	}
-	defer fi.Close()
+/**
+ * 设置默认词典实例
+ * 未指定词典的分词器使用默认词典，包级全局词典指向该实例当前的快照，之后不再更新
+ * 应在开始分词之前调用
+ */
+func SetDefaultDictionary(d *Dictionary) {
+	defaultDict = d
+	syncGlobals(d)
+}
 
-	br := bufio.NewReader(fi)
-	for {
-		word, _, c := br.ReadLine()
-		if c == io.EOF {
-			break
-		}
-		if word != nil {
-			trimWord := bytes.Trim(word, "\r\n\t ")
-			if !bytes.Equal(trimWord, []byte("")) {
-				QuantifierDict.fillSegment([]rune(string(trimWord)))
-			}
+/**
+ * 将包级全局词典指向词典实例的当前快照，只在设置默认词典时调用
+ */
+func syncGlobals(d *Dictionary) {
+	set := d.load()
+	conf_dir = d.confDir
+	ext_files = set.extFiles
+	ext_stopfiles = set.extStopFiles
+
+	MainDict = set.mainDict
+	SurnameDict = set.surnameDict
+	QuantifierDict = set.quantifierDict
+	SuffixDict = set.suffixDict
+	PrepDict = set.prepDict
+	StopWords = set.stopWords
+}
 
-		}
+/**
+ * 返回默认词典实例
+ * 未调用InitDict时，使用包级全局词典（未赋值的词典视为空词典）
+ */
+func defaultDictionary() *Dictionary {
+	if defaultDict != nil {
+		return defaultDict
+	}
+	return newDictionaryWithSet(&dictSet{
+		mainDict:       orEmptyDict(MainDict),
+		surnameDict:    orEmptyDict(SurnameDict),
+		quantifierDict: orEmptyDict(QuantifierDict),
+		suffixDict:     orEmptyDict(SuffixDict),
+		prepDict:       orEmptyDict(PrepDict),
+		stopWords:      orEmptyDict(StopWords),
+		report:         &LoadReport{},
+	}, &loadOptions{})
+}
+
+func orEmptyDict(ds *DictSegment) *DictSegment {
+	if ds == nil {
+		return NewDictSegment(0)
 	}
+	return ds
 }
 
 /**
- * 加载量词词典
+ * 返回当前词典的加载报告
  */
-func loadSurnameDict() {
-	// 建立一个量词典实例
-	SurnameDict = NewDictSegment(0)
+func (d *Dictionary) Report() *LoadReport {
+	return d.load().report
+}
 
-	// 读取主词典文件
-	fi, err := os.Open(fmt.Sprintf("%s/%s", conf_dir, PATH_DIC_SURNAME))
+/**
+ * 读取IKAnalyzer.cfg.xml中的扩展词典配置
+ * 配置文件不存在时不做处理
+ */
+func (set *dictSet) loadConfig(fsys fs.FS) error {
+	content, err := fs.ReadFile(fsys, FILE_NAME)
 	if err != nil {
-		return
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return &DictLoadError{File: FILE_NAME, Err: err}
 	}
-	defer fi.Close()
-
-	br := bufio.NewReader(fi)
-	for {
-		word, _, c := br.ReadLine()
-		if c == io.EOF {
-			break
+	dfs := &DictFiles{}
+	if err = xml.Unmarshal(content, dfs); err != nil {
+		return &DictLoadError{File: FILE_NAME, Err: err}
+	}
+	for _, df := range dfs.DictFiles {
+		if df.Key == EXT_DICT {
+			set.extFiles = splitDictPaths(df.Path)
+		} else if df.Key == EXT_STOP {
+			set.extStopFiles = splitDictPaths(df.Path)
+		} else if df.Key == REMOTE_EXT_DICT {
+			set.remoteExtFiles = splitRemotePaths(df.Path)
+		} else if df.Key == REMOTE_EXT_STOP {
+			set.remoteExtStopFiles = splitRemotePaths(df.Path)
 		}
-		if word != nil {
-			trimWord := bytes.Trim(word, "\r\n\t ")
-			if !bytes.Equal(trimWord, []byte("")) {
-				SurnameDict.fillSegment([]rune(string(trimWord)))
-			}
+	}
+	return nil
+}
 
+/**
+ * 拆分配置中以分号分隔的词典路径，忽略空项
+ * 路径一律视为相对于词典目录，转换为fs.FS可接受的形式
+ */
+func splitDictPaths(paths string) []string {
+	files := []string{}
+	for _, p := range strings.Split(paths, ";") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			files = append(files, strings.TrimPrefix(path.Clean("/"+p), "/"))
 		}
 	}
+	return files
 }
 
-func loadSuffixDict() {
-	// 建立一个量词典实例
-	SuffixDict = NewDictSegment(0)
+/**
+ * 逐行读取词典文件，填充到指定的词典树，并记录到加载报告
+ * @param ds 词典树
+ * @param fname 相对于词典目录的文件名
+ * @param optional 是否为可选词典
+ */
+func (set *dictSet) fillDictFile(fsys fs.FS, ds *DictSegment, fname string, optional bool) error {
+	stat := DictFileStat{Name: fname, Optional: optional}
+	stat.Words, stat.Err = readDictFile(fsys, ds, fname)
+	if stat.Err != nil {
+		stat.Err = &DictLoadError{File: fname, Optional: optional, Err: stat.Err}
+	}
+	set.report.Files = append(set.report.Files, stat)
+	return stat.Err
+}
 
-	// 读取主词典文件
-	fi, err := os.Open(fmt.Sprintf("%s/%s", conf_dir, PATH_DIC_SUFFIX))
+func readDictFile(fsys fs.FS, ds *DictSegment, fname string) (int, error) {
+	fi, err := fsys.Open(fname)
 	if err != nil {
-		return
+		return 0, err
 	}
 	defer fi.Close()
+	return fillFromReader(ds, fi)
+}
 
-	br := bufio.NewReader(fi)
-	for {
-		word, _, c := br.ReadLine()
-		if c == io.EOF {
-			break
-		}
-		if word != nil {
-			trimWord := bytes.Trim(word, "\r\n\t ")
-			if !bytes.Equal(trimWord, []byte("")) {
-				SuffixDict.fillSegment([]rune(string(trimWord)))
+/**
+ * 解析词典中的一行
+ * 格式为"词"、"词 词频"、"词 词频 词性"或"词 词性"
+ * 只有含非ASCII字符的词才可以省略词频直接跟词性，词性由小写字母组成，如"大学 nt"；
+ * 其他无法解析的行整体作为一个词，如"windows xp"
+ * @return 词、词频及词性，未给出词频时为0，未给出词性时为空
+ */
+func parseDictLine(line string) (word string, freq int, tag string) {
+	fields := strings.Fields(line)
+	if len(fields) == 2 || len(fields) == 3 {
+		if f, err := strconv.Atoi(fields[1]); err == nil && f >= 0 {
+			if len(fields) == 3 {
+				tag = fields[2]
 			}
+			return fields[0], f, tag
+		}
+	}
+	if len(fields) == 2 && isPosTag(fields[1]) && !isASCII(fields[0]) {
+		return fields[0], 0, fields[1]
+	}
+	return line, 0, ""
+}
+
+func isPosTag(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 'a' || s[i] > 'z' {
+			return false
+		}
+	}
+	return len(s) > 0 && len(s) <= 4
+}
 
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
 		}
 	}
+	return true
 }
 
-func loadPrepDict() {
-	// 建立一个量词典实例
-	PrepDict = NewDictSegment(0)
+/**
+ * 未给出词频的词按该词频计算概率
+ */
+const WORD_FREQ_DEFAULT = 1
 
-	// 读取主词典文件
-	fi, err := os.Open(fmt.Sprintf("%s/%s", conf_dir, PATH_DIC_PREP))
-	if err != nil {
-		return
+func wordFreq(freq int) int {
+	if freq <= 0 {
+		return WORD_FREQ_DEFAULT
 	}
-	defer fi.Close()
+	return freq
+}
 
-	br := bufio.NewReader(fi)
+/**
+ * 从io.Reader逐行读取词条，填充到指定的词典树
+ * 每行一个词，可以在词后用空白分隔给出词频及词性，忽略首尾空白及空行
+ * @return 读入的词条数
+ */
+func fillFromReader(ds *DictSegment, r io.Reader) (count int, err error) {
+	err = readDictLines(r, func(word string, freq int, tag string) {
+		chars := []rune(word)
+		ds.fillSegment(chars)
+		if freq > 0 || tag != "" {
+			ds.setWordAttr(chars, freq, tag)
+		}
+		count++
+	})
+	return
+}
+
+/**
+ * 逐行读取词典内容，对每个非空行调用fn
+ */
+func readDictLines(r io.Reader, fn func(word string, freq int, tag string)) (err error) {
+	br := bufio.NewReader(r)
 	for {
 		word, _, c := br.ReadLine()
-		if c == io.EOF {
+		if c != nil {
+			if c != io.EOF {
+				err = c
+			}
 			break
 		}
 		if word != nil {
 			trimWord := bytes.Trim(word, "\r\n\t ")
 			if !bytes.Equal(trimWord, []byte("")) {
-				PrepDict.fillSegment([]rune(string(trimWord)))
+				fn(parseDictLine(string(trimWord)))
 			}
 
 		}
 	}
+	return
+}
+
+/**
+ * 从io.Reader向指定类型的词典追加词条
+ * 与AddWords相同，以写时复制的方式发布新的词典快照，可在分词的同时调用，词典重新加载后依然有效
+ * 读取出错时已读入的词条仍然生效
+ * @param dictType 词典类型，DICT_TYPE_MAIN等
+ * @param r 每行一个词的词典内容
+ * @return 读入的词条数
+ */
+func (d *Dictionary) LoadWords(dictType int, r io.Reader) (int, error) {
+	if d.load().segmentOf(dictType) == nil {
+		return 0, fmt.Errorf("ikgo: unknown dictionary type %d", dictType)
+	}
+	patches := []wordPatch{}
+	err := readDictLines(r, func(word string, freq int, tag string) {
+		patches = append(patches, wordPatch{dictType: dictType, word: []rune(word), enabled: 1, freq: freq, tag: tag})
+	})
+	if perr := d.applyPatches(dictType, patches); perr != nil {
+		return 0, perr
+	}
+	return len(patches), err
+}
+
+/**
+ * 返回词典类型对应的词典树
+ */
+func (set *dictSet) segmentOf(dictType int) *DictSegment {
+	switch dictType {
+	case DICT_TYPE_MAIN:
+		return set.mainDict
+	case DICT_TYPE_SURNAME:
+		return set.surnameDict
+	case DICT_TYPE_QUANTIFIER:
+		return set.quantifierDict
+	case DICT_TYPE_SUFFIX:
+		return set.suffixDict
+	case DICT_TYPE_PREP:
+		return set.prepDict
+	case DICT_TYPE_STOP:
+		return set.stopWords
+	}
+	return nil
 }
 
 /**
@@ -304,8 +651,8 @@ func loadPrepDict() {
  *
  * @return boolean
  */
-func isStopWord(charArray []rune, begin, length int) bool {
-	return StopWords.matchSeg(charArray, begin, length).isMatch()
+func (set *dictSet) isStopWord(charArray []rune, begin, length int) bool {
+	return set.trie(DICT_TYPE_STOP).matchSeg(charArray, begin, length).isMatch()
 }
 
 /**
@@ -314,6 +661,5 @@ func isStopWord(charArray []rune, begin, length int) bool {
  * @return Hit
  */
 func matchWithHit(charArray []rune, currentIndex int, matchedHit *Hit) *Hit {
-	ds := matchedHit.matchedDictSegment
-	return ds.matchSegSearch(charArray, currentIndex, 1, matchedHit)
+	return matchedHit.matchedNode.matchSegSearch(charArray, currentIndex, 1, matchedHit)
 }
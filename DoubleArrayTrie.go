@@ -0,0 +1,324 @@
+package ikgo
+
+import "sort"
+
+/**
+ * 只读的双数组Trie
+ * 由DictSegment整体构建，状态s经字符编码c转移到t = base[s] + c，当且仅当check[t] == s时转移有效
+ * 节点全部存放在几个平坦的数组中，查询时没有指针追逐与Map查找
+ */
+type doubleArrayTrie struct {
+	base     []int32
+	check    []int32
+	freqs    []int32        //词频
+	tags     []uint16       //词性在tagNames中的序号
+	tagNames []string       //词性表，序号0为空
+	flags    []uint8        //DAT_WORD 表示从根到该状态是一个词 ; DAT_PREFIX 表示该状态还有子状态
+	bmp      []int32        //基本多文种平面字符的编码表，0表示字典中没有该字
+	astral   map[rune]int32 //其他平面字符的编码
+
+	//构建时空闲位置组成的双向链表，按位置升序排列，构建完成后释放
+	nextFree, prevFree []int32
+	freeHead, freeTail int32
+	windowHead         int32 //多个子节点的状态从这个空闲位置开始寻找base
+	maxFirst           int32 //多个子节点的状态第一个子节点占用的最大位置
+}
+
+const (
+	DAT_WORD   = 0x01
+	DAT_PREFIX = 0x02
+
+	datEmpty = -1 //check数组中表示位置空闲
+	datRoot  = -2 //根节点的check值，不会与任何状态号相等
+	datNil   = -1 //空闲链表的结束标记
+
+	//构建时多个子节点的状态只在maxFirst之前这么多个位置之后寻找base，
+	//避免每次都从头遍历几乎占满的区域，更早的空闲位置留给只有一个子节点的状态
+	datSearchWindow = 8192
+)
+
+/**
+ * 由DictSegment构建双数组Trie
+ * @param root 词典树根节点
+ */
+func newDoubleArrayTrie(root *DictSegment) *doubleArrayTrie {
+	t := &doubleArrayTrie{astral: make(map[rune]int32), tagNames: []string{""}, freeHead: datNil, freeTail: datNil, windowHead: datNil}
+	maxBMP := rune(-1)
+	chars := map[rune]int{}
+	root.walkChars(func(c rune) {
+		chars[c]++
+		if c < 0x10000 && c > maxBMP {
+			maxBMP = c
+		}
+	})
+	//按出现次数从多到少编码，常见字的编码集中在低位，子节点的位置更紧凑
+	sorted := make([]rune, 0, len(chars))
+	for c := range chars {
+		sorted = append(sorted, c)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if chars[sorted[i]] != chars[sorted[j]] {
+			return chars[sorted[i]] > chars[sorted[j]]
+		}
+		return sorted[i] < sorted[j]
+	})
+	t.bmp = make([]int32, maxBMP+1)
+	var next int32 = 1
+	for _, c := range sorted {
+		if c >= 0 && c < 0x10000 {
+			t.bmp[c] = next
+		} else {
+			t.astral[c] = next
+		}
+		next++
+	}
+
+	t.grow(int(next) + 1)
+	t.use(0, datRoot)
+
+	//广度优先逐层放置子节点
+	type pending struct {
+		ds    *DictSegment
+		state int32
+	}
+	queue := []pending{{root, 0}}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		children := p.ds.sortedChildren()
+		if len(children) == 0 {
+			continue
+		}
+		sort.Slice(children, func(i, j int) bool {
+			return t.code(children[i].nodeChar) < t.code(children[j].nodeChar)
+		})
+		codes := make([]int32, len(children))
+		for i, child := range children {
+			codes[i] = t.code(child.nodeChar)
+		}
+		b := t.findBase(codes)
+		t.base[p.state] = b
+		t.flags[p.state] |= DAT_PREFIX
+		for i, child := range children {
+			s := b + codes[i]
+			t.use(s, p.state)
+			if child.nodeState == 1 {
+				t.flags[s] |= DAT_WORD
+			}
+			t.freqs[s] = int32(child.freq)
+			t.tags[s] = t.tagID(child.tag)
+			queue = append(queue, pending{child, s})
+		}
+	}
+
+	//截掉尾部未使用的位置
+	last := len(t.check) - 1
+	for last > 0 && t.check[last] == datEmpty {
+		last--
+	}
+	t.base = t.base[:last+1]
+	t.check = t.check[:last+1]
+	t.flags = t.flags[:last+1]
+	t.freqs = t.freqs[:last+1]
+	t.tags = t.tags[:last+1]
+	t.nextFree, t.prevFree = nil, nil
+	return t
+}
+
+/**
+ * 遍历词典树中出现的全部字符
+ */
+func (ds *DictSegment) walkChars(fn func(c rune)) {
+	for _, child := range ds.sortedChildren() {
+		fn(child.nodeChar)
+		child.walkChars(fn)
+	}
+}
+
+/**
+ * 返回词性在词性表中的序号，表中没有时追加
+ * 词性的种类很少，直接顺序查找
+ */
+func (t *doubleArrayTrie) tagID(tag string) uint16 {
+	for i, name := range t.tagNames {
+		if name == tag {
+			return uint16(i)
+		}
+	}
+	if len(t.tagNames) > 0xffff {
+		return 0
+	}
+	t.tagNames = append(t.tagNames, tag)
+	return uint16(len(t.tagNames) - 1)
+}
+
+/**
+ * 扩容到至少size个位置
+ */
+func (t *doubleArrayTrie) grow(size int) {
+	if size <= len(t.check) {
+		return
+	}
+	if size < 2*len(t.check) {
+		size = 2 * len(t.check)
+	}
+	old := len(t.check)
+	t.base = append(t.base, make([]int32, size-old)...)
+	t.check = append(t.check, make([]int32, size-old)...)
+	t.flags = append(t.flags, make([]uint8, size-old)...)
+	t.freqs = append(t.freqs, make([]int32, size-old)...)
+	t.tags = append(t.tags, make([]uint16, size-old)...)
+	t.nextFree = append(t.nextFree, make([]int32, size-old)...)
+	t.prevFree = append(t.prevFree, make([]int32, size-old)...)
+	for i := old; i < size; i++ {
+		//新的位置依次接到空闲链表尾部
+		t.check[i] = datEmpty
+		t.prevFree[i], t.nextFree[i] = t.freeTail, datNil
+		if t.freeTail == datNil {
+			t.freeHead = int32(i)
+		} else {
+			t.nextFree[t.freeTail] = int32(i)
+		}
+		t.freeTail = int32(i)
+		if t.windowHead == datNil {
+			t.windowHead = int32(i)
+		}
+	}
+}
+
+/**
+ * 占用位置s并从空闲链表中摘除
+ */
+func (t *doubleArrayTrie) use(s, check int32) {
+	t.check[s] = check
+	prev, next := t.prevFree[s], t.nextFree[s]
+	if s == t.windowHead {
+		t.windowHead = next
+	}
+	if prev == datNil {
+		t.freeHead = next
+	} else {
+		t.nextFree[prev] = next
+	}
+	if next == datNil {
+		t.freeTail = prev
+	} else {
+		t.prevFree[next] = prev
+	}
+}
+
+/**
+ * 寻找一个base值，使得全部子节点的位置都空闲
+ * 只沿空闲链表尝试第一个子节点的位置，已占用的位置不再逐个扫描
+ * 只有一个子节点时第一个空闲位置即可；多个子节点时只在搜索窗口内寻找，每次寻找的代价有上界
+ * @param codes 升序排列的子节点字符编码
+ */
+func (t *doubleArrayTrie) findBase(codes []int32) int32 {
+	if len(codes) == 1 && t.freeHead != datNil {
+		return t.freeHead - codes[0]
+	}
+	for t.windowHead != datNil && t.windowHead < t.maxFirst-datSearchWindow {
+		t.windowHead = t.nextFree[t.windowHead]
+	}
+	pos := t.windowHead
+	for {
+		if pos == datNil {
+			//没有合适的空闲位置，扩容后从新的位置继续
+			old := len(t.check)
+			t.grow(old + 1)
+			pos = int32(old)
+		}
+		//base可以为负数，子节点的位置不小于pos，总在根节点之后
+		b := pos - codes[0]
+		t.grow(int(b+codes[len(codes)-1]) + 1)
+		ok := true
+		for _, c := range codes[1:] {
+			if t.check[b+c] != datEmpty {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			if pos > t.maxFirst {
+				t.maxFirst = pos
+			}
+			return b
+		}
+		pos = t.nextFree[pos]
+	}
+}
+
+/**
+ * 返回字符的编码，字典中没有的字符返回0
+ */
+func (t *doubleArrayTrie) code(c rune) int32 {
+	if c >= 0 && int(c) < len(t.bmp) {
+		return t.bmp[c]
+	}
+	if c < 0x10000 {
+		return 0
+	}
+	return t.astral[c]
+}
+
+/**
+ * 状态s经字符c转移，失败返回-1
+ */
+func (t *doubleArrayTrie) transition(s int32, c rune) int32 {
+	code := t.code(c)
+	if code == 0 {
+		return -1
+	}
+	next := t.base[s] + code
+	if uint(next) < uint(len(t.check)) && t.check[next] == s {
+		return next
+	}
+	return -1
+}
+
+/**
+ * 匹配词段，从根状态开始
+ */
+func (t *doubleArrayTrie) matchSeg(charArray []rune, begin, length int) *Hit {
+	return t.matchSegSearch(charArray, begin, length, nil)
+}
+
+/**
+ * 匹配词段，语义与DictSegment.matchSegSearch一致
+ * searchHit为nil时从根状态开始，否则从searchHit记录的状态继续向下匹配
+ * 状态号保存在Hit中，避免每次前缀命中都分配新的节点对象
+ */
+func (t *doubleArrayTrie) matchSegSearch(charArray []rune, begin, length int, searchHit *Hit) *Hit {
+	var s int32
+	if searchHit == nil {
+		searchHit = &Hit{}
+		searchHit.beg = begin
+	} else {
+		s = searchHit.matchedState
+		searchHit.setUnmatch()
+	}
+
+	for i := begin; i < begin+length; i++ {
+		searchHit.end = i
+		if t.flags[s]&DAT_PREFIX == 0 {
+			return searchHit
+		}
+		s = t.transition(s, charArray[i])
+		if s < 0 {
+			return searchHit
+		}
+	}
+
+	flags := t.flags[s]
+	if flags&DAT_WORD != 0 {
+		searchHit.setMatch()
+		searchHit.freq = int(t.freqs[s])
+		searchHit.tag = t.tagNames[t.tags[s]]
+	}
+	if flags&DAT_PREFIX != 0 {
+		searchHit.setPrefix()
+		searchHit.matchedNode = t
+		searchHit.matchedState = s
+	}
+	return searchHit
+}
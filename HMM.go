@@ -0,0 +1,336 @@
+package ikgo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/**
+ * 未登录词识别使用的BMES隐马尔可夫模型
+ * B词首、M词中、E词尾、S单字成词，概率均为自然对数
+ */
+const (
+	HMM_STATE_B = iota
+	HMM_STATE_M
+	HMM_STATE_E
+	HMM_STATE_S
+
+	hmmStateCount = 4
+)
+
+var hmmStateNames = [hmmStateCount]string{"B", "M", "E", "S"}
+
+// 合法的前一状态
+var hmmPrevStates = [hmmStateCount][]int{
+	HMM_STATE_B: {HMM_STATE_E, HMM_STATE_S},
+	HMM_STATE_M: {HMM_STATE_B, HMM_STATE_M},
+	HMM_STATE_E: {HMM_STATE_B, HMM_STATE_M},
+	HMM_STATE_S: {HMM_STATE_E, HMM_STATE_S},
+}
+
+type HMMModel struct {
+	start   [hmmStateCount]float64
+	trans   [hmmStateCount][hmmStateCount]float64
+	emit    [hmmStateCount]map[rune]float64
+	minEmit [hmmStateCount]float64 //未出现过的字的发射概率
+}
+
+func newHMMModel() *HMMModel {
+	m := &HMMModel{}
+	for i := 0; i < hmmStateCount; i++ {
+		m.start[i] = math.Inf(-1)
+		for j := 0; j < hmmStateCount; j++ {
+			m.trans[i][j] = math.Inf(-1)
+		}
+		m.emit[i] = make(map[rune]float64)
+		m.minEmit[i] = math.Inf(-1)
+	}
+	return m
+}
+
+/**
+ * 由词典中的词训练模型
+ * 每个词按词频计数：单字词为S，多字词依次为B、M...、E；词与词之间的转移按单字词与多字词的比例估计
+ * 统计量做加一平滑，未出现过的字使用平滑后的最小概率
+ * @param d 词典实例
+ */
+func NewHMMModelFromDictionary(d *Dictionary) *HMMModel {
+	return trainHMMModel(d.load().mainDict)
+}
+
+func trainHMMModel(root *DictSegment) *HMMModel {
+	var emitCount [hmmStateCount]map[rune]float64
+	for i := range emitCount {
+		emitCount[i] = make(map[rune]float64)
+	}
+	var transCount [hmmStateCount][hmmStateCount]float64
+	var single, multi float64
+
+	chars := make([]rune, 0, 16)
+	var walk func(ds *DictSegment)
+	walk = func(ds *DictSegment) {
+		for _, child := range ds.sortedChildren() {
+			chars = append(chars, child.nodeChar)
+			if child.nodeState == 1 {
+				w := float64(wordFreq(child.freq))
+				n := len(chars)
+				if n == 1 {
+					emitCount[HMM_STATE_S][chars[0]] += w
+					single += w
+				} else {
+					multi += w
+					emitCount[HMM_STATE_B][chars[0]] += w
+					emitCount[HMM_STATE_E][chars[n-1]] += w
+					for _, c := range chars[1 : n-1] {
+						emitCount[HMM_STATE_M][c] += w
+					}
+					if n == 2 {
+						transCount[HMM_STATE_B][HMM_STATE_E] += w
+					} else {
+						transCount[HMM_STATE_B][HMM_STATE_M] += w
+						transCount[HMM_STATE_M][HMM_STATE_M] += w * float64(n-3)
+						transCount[HMM_STATE_M][HMM_STATE_E] += w
+					}
+				}
+			}
+			walk(child)
+			chars = chars[:len(chars)-1]
+		}
+	}
+	walk(root)
+
+	m := newHMMModel()
+	//词与词之间的转移及初始状态
+	pB := math.Log((multi + 1) / (multi + single + 2))
+	pS := math.Log((single + 1) / (multi + single + 2))
+	m.start[HMM_STATE_B], m.start[HMM_STATE_S] = pB, pS
+	for _, from := range []int{HMM_STATE_E, HMM_STATE_S} {
+		m.trans[from][HMM_STATE_B], m.trans[from][HMM_STATE_S] = pB, pS
+	}
+	for _, from := range []int{HMM_STATE_B, HMM_STATE_M} {
+		total := transCount[from][HMM_STATE_M] + transCount[from][HMM_STATE_E] + 2
+		m.trans[from][HMM_STATE_M] = math.Log((transCount[from][HMM_STATE_M] + 1) / total)
+		m.trans[from][HMM_STATE_E] = math.Log((transCount[from][HMM_STATE_E] + 1) / total)
+	}
+
+	//发射概率
+	vocabulary := make(map[rune]bool)
+	for _, counts := range emitCount {
+		for c := range counts {
+			vocabulary[c] = true
+		}
+	}
+	for state, counts := range emitCount {
+		total := float64(len(vocabulary)) + 1
+		for _, count := range counts {
+			total += count
+		}
+		for c, count := range counts {
+			m.emit[state][c] = math.Log((count + 1) / total)
+		}
+		m.minEmit[state] = math.Log(1 / total)
+	}
+	return m
+}
+
+/**
+ * 发射概率
+ */
+func (m *HMMModel) emitProb(state int, c rune) float64 {
+	if p, exists := m.emit[state][c]; exists {
+		return p
+	}
+	return m.minEmit[state]
+}
+
+/**
+ * Viterbi解码，返回每个字的BMES状态
+ * 首字只能是B或S，末字只能是E或S
+ * @param chars 连续的中文字符
+ */
+func (m *HMMModel) viterbi(chars []rune) []int {
+	n := len(chars)
+	prob := make([][hmmStateCount]float64, n)
+	prev := make([][hmmStateCount]int, n)
+	for s := 0; s < hmmStateCount; s++ {
+		prob[0][s] = m.start[s] + m.emitProb(s, chars[0])
+	}
+	for i := 1; i < n; i++ {
+		for s := 0; s < hmmStateCount; s++ {
+			best, bestPrev := math.Inf(-1), hmmPrevStates[s][0]
+			for _, p := range hmmPrevStates[s] {
+				if score := prob[i-1][p] + m.trans[p][s]; score > best {
+					best, bestPrev = score, p
+				}
+			}
+			prob[i][s] = best + m.emitProb(s, chars[i])
+			prev[i][s] = bestPrev
+		}
+	}
+
+	states := make([]int, n)
+	states[n-1] = HMM_STATE_E
+	if prob[n-1][HMM_STATE_S] > prob[n-1][HMM_STATE_E] {
+		states[n-1] = HMM_STATE_S
+	}
+	for i := n - 1; i > 0; i-- {
+		states[i-1] = prev[i][states[i]]
+	}
+	return states
+}
+
+/**
+ * 将连续的中文字符切分为词
+ * @return 每个词的长度
+ */
+func (m *HMMModel) cut(chars []rune) []int {
+	lengths := []int{}
+	begin := 0
+	for i, state := range m.viterbi(chars) {
+		if state == HMM_STATE_E || state == HMM_STATE_S {
+			lengths = append(lengths, i-begin+1)
+			begin = i + 1
+		}
+	}
+	//解码结果不完整时剩余部分按单字处理
+	for ; begin < len(chars); begin++ {
+		lengths = append(lengths, 1)
+	}
+	return lengths
+}
+
+/**
+ * 以文本格式写出模型，可由LoadHMMModel读回
+ * 每行为以下三种之一，概率为自然对数：
+ *   start <状态> <概率>
+ *   trans <状态> <状态> <概率>
+ *   emit <状态> <字> <概率>
+ * 未出现过的字的发射概率记为 emit <状态> * <概率>
+ */
+func (m *HMMModel) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var n int64
+	write := func(format string, args ...interface{}) {
+		c, _ := fmt.Fprintf(bw, format, args...)
+		n += int64(c)
+	}
+	for s := 0; s < hmmStateCount; s++ {
+		write("start %s %g\n", hmmStateNames[s], m.start[s])
+	}
+	for from := 0; from < hmmStateCount; from++ {
+		for to := 0; to < hmmStateCount; to++ {
+			if !math.IsInf(m.trans[from][to], -1) {
+				write("trans %s %s %g\n", hmmStateNames[from], hmmStateNames[to], m.trans[from][to])
+			}
+		}
+	}
+	for s := 0; s < hmmStateCount; s++ {
+		write("emit %s * %g\n", hmmStateNames[s], m.minEmit[s])
+		for _, c := range sortedRunes(m.emit[s]) {
+			write("emit %s %c %g\n", hmmStateNames[s], c, m.emit[s][c])
+		}
+	}
+	return n, bw.Flush()
+}
+
+func sortedRunes(emit map[rune]float64) []rune {
+	runes := make([]rune, 0, len(emit))
+	for c := range emit {
+		runes = append(runes, c)
+	}
+	sort.Slice(runes, func(i, j int) bool {
+		return runes[i] < runes[j]
+	})
+	return runes
+}
+
+func hmmState(name string) (int, error) {
+	for s, n := range hmmStateNames {
+		if n == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("ikgo: unknown hmm state %q", name)
+}
+
+/**
+ * 读取WriteTo写出的模型
+ * 忽略空行及#开头的注释行
+ */
+func LoadHMMModel(r io.Reader) (*HMMModel, error) {
+	m := newHMMModel()
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if err := m.parseLine(strings.Fields(text)); err != nil {
+			return nil, fmt.Errorf("ikgo: hmm model line %d: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *HMMModel) parseLine(fields []string) error {
+	if len(fields) < 3 {
+		return fmt.Errorf("malformed line")
+	}
+	p, err := strconv.ParseFloat(fields[len(fields)-1], 64)
+	if err != nil {
+		return err
+	}
+	s, err := hmmState(fields[1])
+	if err != nil {
+		return err
+	}
+	switch {
+	case fields[0] == "start" && len(fields) == 3:
+		m.start[s] = p
+	case fields[0] == "trans" && len(fields) == 4:
+		to, err := hmmState(fields[2])
+		if err != nil {
+			return err
+		}
+		m.trans[s][to] = p
+	case fields[0] == "emit" && len(fields) == 4:
+		if fields[2] == "*" {
+			m.minEmit[s] = p
+			break
+		}
+		chars := []rune(fields[2])
+		if len(chars) != 1 {
+			return fmt.Errorf("emit expects a single character, got %q", fields[2])
+		}
+		m.emit[s][chars[0]] = p
+	default:
+		return fmt.Errorf("malformed line")
+	}
+	return nil
+}
+
+/**
+ * 由词典训练的默认模型，每个词典快照在第一次使用时训练一次
+ */
+type lazyHMMModel struct {
+	once  sync.Once
+	model *HMMModel
+}
+
+func (set *dictSet) hmmModel() *HMMModel {
+	set.hmm.once.Do(func() {
+		set.hmm.model = trainHMMModel(set.mainDict)
+	})
+	return set.hmm.model
+}
@@ -7,9 +7,12 @@ const (
 )
 
 type Hit struct {
-	hitState           int          //该HIT当前状态，默认未匹配
-	matchedDictSegment *DictSegment //记录词典匹配过程中，当前匹配到的词典分支节点
-	beg, end           int          //词段起止位置
+	hitState     int      //该HIT当前状态，默认未匹配
+	matchedNode  trieNode //记录词典匹配过程中，当前匹配到的词典分支节点
+	matchedState int32    //双数组Trie中当前匹配到的状态
+	freq         int      //完全匹配时词的词频
+	tag          string   //完全匹配时词的词性
+	beg, end     int      //词段起止位置
 }
 
 /**
@@ -4,7 +4,30 @@ import (
 	"container/list"
 )
 
+/**
+ * IK默认的歧义处理策略
+ * 依次比较有效文本长度、虚词粘连数、词元个数、路径跨度、位置权重，选出最优的切分
+ * 虚词粘连数为首字或尾字是介词（及停止词）的多字词元个数，粘连少的切分优先，
+ * 如"是非常"中"是"为介词词典中的虚词，"是/非常"优于"是非/常"
+ */
 type IKArbitrator struct {
+	IgnorePrepositions bool //不参考介词词典，与原版IK的结果一致
+	UseStopwords       bool //同时参考停止词词典
+}
+
+/**
+ * 返回判断虚词的函数，不参考任何词典时返回nil
+ */
+func (a IKArbitrator) functionChar(context *AnalyzeContext) func(index int) bool {
+	if a.IgnorePrepositions && !a.UseStopwords {
+		return nil
+	}
+	return func(index int) bool {
+		if !a.IgnorePrepositions && context.dicts.trie(DICT_TYPE_PREP).matchSeg(context.segmentBuff, index, 1).isMatch() {
+			return true
+		}
+		return a.UseStopwords && context.dicts.isStopWord(context.segmentBuff, index, 1)
+	}
 }
 
 /**
@@ -12,7 +35,7 @@ type IKArbitrator struct {
  * @param LexemePath path
  * @return
  */
-func (a *IKArbitrator) forwardPath(lexemeCell *Cell, option *LexemePath) *list.List {
+func (a IKArbitrator) forwardPath(lexemeCell *Cell, option *LexemePath) *list.List {
 	//发生冲突的Lexeme栈
 	conflictStack := list.New()
 	c := lexemeCell
@@ -32,7 +55,7 @@ func (a *IKArbitrator) forwardPath(lexemeCell *Cell, option *LexemePath) *list.L
  * @param lexeme
  * @param l
  */
-func (a *IKArbitrator) backPath(l *Lexeme, option *LexemePath) {
+func (a IKArbitrator) backPath(l *Lexeme, option *LexemePath) {
 	for option.checkCross(l) {
 		option.removeTail()
 	}
@@ -42,9 +65,10 @@ func (a *IKArbitrator) backPath(l *Lexeme, option *LexemePath) {
  * 歧义识别
  * @param lexemeCell 歧义路径链表头
  * @param fullTextLength 歧义路径文本长度
+ * @param functionChar 判断指定位置的字是否为虚词，为nil时不比较虚词粘连数
  * @return
  */
-func (a *IKArbitrator) judge(lexemeCell *Cell, fullTextLength int) *LexemePath {
+func (a IKArbitrator) judge(lexemeCell *Cell, fullTextLength int, functionChar func(index int) bool) *LexemePath {
 	//候选路径集合
 	pathOptions := []*LexemePath{}
 	//候选结果路径
@@ -69,6 +93,12 @@ func (a *IKArbitrator) judge(lexemeCell *Cell, fullTextLength int) *LexemePath {
 		pathOptions = append(pathOptions, option.deepCopy())
 	}
 
+	if functionChar != nil {
+		for _, po := range pathOptions {
+			po.glued = po.countGlued(functionChar)
+		}
+	}
+
 	//返回集合中的最优方案
 	best := pathOptions[0]
 	for _, po := range pathOptions {
@@ -79,12 +109,20 @@ func (a *IKArbitrator) judge(lexemeCell *Cell, fullTextLength int) *LexemePath {
 	return best
 }
 
+/**
+ * 实现Arbitrator接口
+ */
+func (a IKArbitrator) Arbitrate(path *CrossPath) []*Lexeme {
+	return a.judge(path.path.set.head, path.path.getPathLength(), a.functionChar(path.ac)).lexemes()
+}
+
 /**
  * 分词歧义处理
- * @param orgLexemes
+ * @param context
  * @param useSmart
+ * @param arbitrator 歧义处理策略
  */
-func (a *IKArbitrator) process(context *AnalyzeContext, useSmart bool) {
+func process(context *AnalyzeContext, useSmart bool, arbitrator Arbitrator) {
 	orgLexemes := context.getOrgLexemes()
 	orgLexeme := orgLexemes.pollFirst()
 
@@ -92,17 +130,7 @@ func (a *IKArbitrator) process(context *AnalyzeContext, useSmart bool) {
 	for orgLexeme != nil {
 		if !crossPath.addCrossLexeme(orgLexeme) {
 			//找到与crossPath不相交的下一个crossPath
-			if crossPath.set.size == 1 || !useSmart {
-				//crossPath没有歧义 或者 不做歧义处理
-				//直接输出当前crossPath
-				context.addLexemePath(crossPath)
-			} else {
-				//对当前的crossPath进行歧义处理
-				headCell := crossPath.set.head
-				judgeResult := a.judge(headCell, crossPath.getPathLength())
-				//输出歧义处理结果judgeResult
-				context.addLexemePath(judgeResult)
-			}
+			outputCrossPath(context, crossPath, useSmart, arbitrator)
 
 			//把orgLexeme加入新的crossPath中
 			crossPath = NewLexemePath()
@@ -113,15 +141,24 @@ func (a *IKArbitrator) process(context *AnalyzeContext, useSmart bool) {
 	}
 
 	//处理最后的path
-	if crossPath.set.size == 1 || !useSmart {
+	outputCrossPath(context, crossPath, useSmart, arbitrator)
+}
+
+func outputCrossPath(context *AnalyzeContext, crossPath *LexemePath, useSmart bool, arbitrator Arbitrator) {
+	if crossPath.set.size <= 1 || !useSmart {
 		//crossPath没有歧义 或者 不做歧义处理
 		//直接输出当前crossPath
 		context.addLexemePath(crossPath)
-	} else {
-		//对当前的crossPath进行歧义处理
-		headCell := crossPath.set.head
-		judgeResult := a.judge(headCell, crossPath.getPathLength())
-		//输出歧义处理结果judgeResult
-		context.addLexemePath(judgeResult)
+		return
+	}
+
+	//对当前的crossPath进行歧义处理
+	judgeResult := arbitratePath(arbitrator, crossPath, context)
+	if judgeResult == nil {
+		//自定义策略返回了不合法的结果，使用IK默认策略
+		a := IKArbitrator{}
+		judgeResult = a.judge(crossPath.set.head, crossPath.getPathLength(), a.functionChar(context))
 	}
+	//输出歧义处理结果judgeResult
+	context.addLexemePath(judgeResult)
 }
@@ -2,6 +2,7 @@ package ikgo
 
 import (
 	"bufio"
+	"io"
 	"strings"
 )
 
@@ -11,35 +12,68 @@ type IKSegmenter struct {
 	reader     *bufio.Reader
 	context    *AnalyzeContext
 	segmenters []ISegmenter
-	arbitrator IKArbitrator
+	arbitrator Arbitrator
 	useSmart   bool
+	err        error
+	position   int //下一个输出词元的序号
 }
 
 func init() {
 	initCNQS()
 	initLS()
+	initPS()
+	initSS()
+	initTS()
 }
 
-func NewIKSegmenter(input string, useSmart bool) *IKSegmenter {
+/**
+ * 创建分词器
+ * @param input 待分词文本
+ * @param opts 分词选项，如WithSmart、WithDictionary
+ */
+func NewIKSegmenter(input string, opts ...Option) *IKSegmenter {
+	return NewIKSegmenterFromReader(strings.NewReader(input), opts...)
+}
+
+/**
+ * 从reader流式读取文本进行分词，不需要将全部文本读入内存
+ * @param input 待分词文本
+ * @param opts 分词选项
+ */
+func NewIKSegmenterFromReader(input io.Reader, opts ...Option) *IKSegmenter {
+	o := newSegmentOptions(opts)
 	ret := &IKSegmenter{
-		reader:     bufio.NewReader(strings.NewReader(input)),
-		context:    NewAnalyzeContext(useSmart),
-		arbitrator: IKArbitrator{},
-		useSmart:   useSmart,
+		reader:     bufio.NewReader(input),
+		context:    newAnalyzeContext(o.useSmart, o.dict, o.bufferSize),
+		arbitrator: o.arbitrator,
+		useSmart:   o.useSmart,
 	}
-	ret.loadSegmenters()
+	ret.context.useStopwords = o.useStopwords
+	ret.context.usePersonNames = o.usePersonNames
+	ret.context.usePlaceOrgNames = o.usePlaceOrgNames
+	ret.context.useSubTokens = o.useSubTokens
+	ret.context.quantifierPolicy = o.quantifierPolicy
+	ret.context.useNumeralFilter = o.useNumeralFilter
+	ret.context.useTemporal = o.useTemporal
+	ret.context.referenceTime = o.referenceTime
+	ret.context.useHMM = o.useHMM
+	ret.context.hmm = o.hmm
+	ret.loadSegmenters(o.newSegmenters, o.newCustom)
 	return ret
 }
 
 /**
- * 初始化词典，加载子分词器实现
- * @return List<ISegmenter>
+ * 加载子分词器实现
+ * @param newSegmenters 子分词器的构造函数
+ * @param newCustom 自定义子分词器的构造函数
  */
-func (s *IKSegmenter) loadSegmenters() {
-	s.segmenters = []ISegmenter{
-		NewLetterSegmenter(),
-		NewCN_QuantifierSegmenter(),
-		NewCJKSegmenter(),
+func (s *IKSegmenter) loadSegmenters(newSegmenters []func() ISegmenter, newCustom []func() Segmenter) {
+	s.segmenters = make([]ISegmenter, 0, len(newSegmenters)+len(newCustom))
+	for _, newSegmenter := range newSegmenters {
+		s.segmenters = append(s.segmenters, newSegmenter())
+	}
+	for i, newSegmenter := range newCustom {
+		s.segmenters = append(s.segmenters, newCustomSegmenter(newSegmenter(), i))
 	}
 }
 
@@ -56,7 +90,13 @@ func (s *IKSegmenter) Next() *Lexeme {
 		 * 如果reader是分次读入buffer的，那么buffer要  进行移位处理
 		 * 移位处理上次读入的但未处理的数据
 		 */
-		available := s.context.fillBuffer(s.reader)
+		if s.err != nil {
+			//上一轮读取出错，不再继续读取
+			s.context.reset()
+			return nil
+		}
+		available, err := s.context.fillBuffer(s.reader)
+		s.err = err
 		if available <= 0 {
 			//reader已经读完
 			s.context.reset()
@@ -87,7 +127,7 @@ func (s *IKSegmenter) Next() *Lexeme {
 		}
 
 		//对分词进行歧义处理
-		s.arbitrator.process(s.context, s.useSmart)
+		process(s.context, s.useSmart, s.arbitrator)
 		//将分词结果输出到结果集，并处理未切分的单个CJK字符
 		s.context.outputToResult()
 		//记录本次分词的缓冲区位移
@@ -95,6 +135,8 @@ func (s *IKSegmenter) Next() *Lexeme {
 
 		l = s.context.getNextLexeme()
 	}
+	l.position = s.position
+	s.position++
 	return l
 }
 
@@ -103,9 +145,27 @@ func (s *IKSegmenter) Next() *Lexeme {
  * @param input
  */
 func (s *IKSegmenter) Reset(input string) {
+	s.ResetReader(strings.NewReader(input))
+}
+
+/**
+ * 重置分词器到初始状态，改为从新的reader读取文本
+ * @param input
+ */
+func (s *IKSegmenter) ResetReader(input io.Reader) {
 	s.context.reset()
 	for _, segmenter := range s.segmenters {
 		segmenter.reset()
 	}
-	s.reader = bufio.NewReader(strings.NewReader(input))
+	s.reader = bufio.NewReader(input)
+	s.err = nil
+	s.position = 0
+}
+
+/**
+ * 返回读取文本时遇到的错误，io.EOF不视为错误
+ * Next返回nil后，可以通过Err判断是文本读完还是读取出错
+ */
+func (s *IKSegmenter) Err() error {
+	return s.err
 }
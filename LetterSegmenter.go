@@ -139,8 +139,8 @@ func (s *LetterSegmenter) processEnglishLetter(context *AnalyzeContext) bool {
 		//缓冲已读完，输出词元
 		newLexeme := NewLexeme(context.bufOffset, s.englishStart, s.englishEnd-s.englishStart+1, LEXEME_TYPE_ENGLISH)
 		context.addLexeme(newLexeme)
-		s.start = -1
-		s.end = -1
+		s.englishStart = -1
+		s.englishEnd = -1
 	}
 
 	//判断是否锁定缓冲区
@@ -188,8 +188,8 @@ func (s *LetterSegmenter) processArabicLetter(context *AnalyzeContext) bool {
 		//缓冲以读完，输出词元
 		newLexeme := NewLexeme(context.bufOffset, s.arabicStart, s.arabicEnd-s.arabicStart+1, LEXEME_TYPE_ARABIC)
 		context.addLexeme(newLexeme)
-		s.start = -1
-		s.end = -1
+		s.arabicStart = -1
+		s.arabicEnd = -1
 	}
 
 	//判断是否锁定缓冲区
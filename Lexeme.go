@@ -1,29 +1,105 @@
 package ikgo
 
+import "fmt"
+
 /**
  * IK词元对象
  */
 
+/**
+ * 词元类型
+ * 数值与IK Analyzer保持一致，序列化时使用String返回的名称
+ */
+type LexemeType int
+
 const (
-	LEXEME_TYPE_UNKNOWN   = 0
-	LEXEME_TYPE_ENGLISH   = 1
-	LEXEME_TYPE_ARABIC    = 2
-	LEXEME_TYPE_LETTER    = 3
-	LEXEME_TYPE_CNWORD    = 4
-	LEXEME_TYPE_CNCHAR    = 64
-	LEXEME_TYPE_OTHER_CJK = 8
-	LEXEME_TYPE_CNUM      = 16
-	LEXEME_TYPE_COUNT     = 32
-	LEXEME_TYPE_CQUAN     = 48
+	LEXEME_TYPE_UNKNOWN   LexemeType = 0
+	LEXEME_TYPE_ENGLISH   LexemeType = 1
+	LEXEME_TYPE_ARABIC    LexemeType = 2
+	LEXEME_TYPE_LETTER    LexemeType = 3
+	LEXEME_TYPE_CNWORD    LexemeType = 4
+	LEXEME_TYPE_CNCHAR    LexemeType = 64
+	LEXEME_TYPE_OTHER_CJK LexemeType = 8
+	LEXEME_TYPE_CNUM      LexemeType = 16
+	LEXEME_TYPE_COUNT     LexemeType = 32
+	LEXEME_TYPE_CQUAN     LexemeType = 48
+	LEXEME_TYPE_OOV       LexemeType = 128  //隐马尔可夫模型识别的未登录词
+	LEXEME_TYPE_PERSON    LexemeType = 256  //人名
+	LEXEME_TYPE_PLACE     LexemeType = 512  //地名
+	LEXEME_TYPE_ORG       LexemeType = 1024 //机构名
+	LEXEME_TYPE_DATE      LexemeType = 2048 //日期
+	LEXEME_TYPE_TIME      LexemeType = 4096 //时刻
+	LEXEME_TYPE_DURATION  LexemeType = 8192 //时长
 )
 
+// 词元类型名称
+var lexemeTypeNames = map[LexemeType]string{
+	LEXEME_TYPE_UNKNOWN:   "UNKNOWN",
+	LEXEME_TYPE_ENGLISH:   "ENGLISH",
+	LEXEME_TYPE_ARABIC:    "ARABIC",
+	LEXEME_TYPE_LETTER:    "LETTER",
+	LEXEME_TYPE_CNWORD:    "CN_WORD",
+	LEXEME_TYPE_CNCHAR:    "CN_CHAR",
+	LEXEME_TYPE_OTHER_CJK: "OTHER_CJK",
+	LEXEME_TYPE_CNUM:      "CN_NUM",
+	LEXEME_TYPE_COUNT:     "COUNT",
+	LEXEME_TYPE_CQUAN:     "CN_QUAN",
+	LEXEME_TYPE_OOV:       "OOV",
+	LEXEME_TYPE_PERSON:    "PERSON",
+	LEXEME_TYPE_PLACE:     "PLACE",
+	LEXEME_TYPE_ORG:       "ORG",
+	LEXEME_TYPE_DATE:      "DATE",
+	LEXEME_TYPE_TIME:      "TIME",
+	LEXEME_TYPE_DURATION:  "DURATION",
+}
+
+// 旧版本GetTypeString输出的名称，解析时兼容
+var legacyLexemeTypeNames = map[string]LexemeType{
+	"TYPE_CNUM":  LEXEME_TYPE_CNUM,
+	"TYPE_CQUAN": LEXEME_TYPE_CQUAN,
+}
+
+func (t LexemeType) String() string {
+	if name, exists := lexemeTypeNames[t]; exists {
+		return name
+	}
+	return lexemeTypeNames[LEXEME_TYPE_UNKNOWN]
+}
+
+func (t LexemeType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *LexemeType) UnmarshalText(text []byte) error {
+	name := string(text)
+	for lt, n := range lexemeTypeNames {
+		if n == name {
+			*t = lt
+			return nil
+		}
+	}
+	if lt, exists := legacyLexemeTypeNames[name]; exists {
+		*t = lt
+		return nil
+	}
+	return fmt.Errorf("ikgo: unknown lexeme type %q", name)
+}
+
 type Lexeme struct {
 	offset, begin, length int
 	lexemeText            string
-	lexemeType            int
+	lexemeType            LexemeType
+	position              int       //词元在输出序列中的序号，从0开始
+	byteBegin, byteEnd    int       //词元在原始输入中的字节位置
+	utf16Begin, utf16End  int       //词元在文本中的UTF-16码元位置
+	freq                  int       //词典中的词频，非词典词或未给出词频时为0
+	tag                   string    //词性，见POS_TAG_*
+	parts                 []*Lexeme //地名、机构名等实体的组成部分
+	value                 string    //规范化取值，如中文数词对应的阿拉伯数字
+	period                string    //时间词元的时段，TIMEX3记法，如"EV"
 }
 
-func NewLexeme(offset, begin, length, lexemeType int) (l *Lexeme) {
+func NewLexeme(offset, begin, length int, lexemeType LexemeType) (l *Lexeme) {
 	if length < 0 {
 		l = nil
 		return
@@ -119,29 +195,89 @@ func (l *Lexeme) GetLength() int {
 	return l.length
 }
 
+/**
+ * 获取词元在原始输入中的起始字节位置，可直接用于切分Go字符串
+ * @return int
+ */
+func (l *Lexeme) GetByteStart() int {
+	return l.byteBegin
+}
+
+/**
+ * 获取词元在原始输入中的结束字节位置
+ * @return int
+ */
+func (l *Lexeme) GetByteEnd() int {
+	return l.byteEnd
+}
+
+/**
+ * 获取词元以UTF-16码元计的起始位置，与JavaScript字符串下标一致
+ * @return int
+ */
+func (l *Lexeme) GetUTF16Start() int {
+	return l.utf16Begin
+}
+
+/**
+ * 获取词元以UTF-16码元计的结束位置
+ * @return int
+ */
+func (l *Lexeme) GetUTF16End() int {
+	return l.utf16End
+}
+
+/**
+ * 获取词元在词典中的词频，非词典词或词典未给出词频时为0
+ * @return int
+ */
+func (l *Lexeme) GetFreq() int {
+	return l.freq
+}
+
+/**
+ * 获取词元的词性
+ * 词典词取词典中给出的词性，未给出时为空；其他词元按规则推测，见POS_TAG_*
+ * @return string
+ */
+func (l *Lexeme) GetTag() string {
+	return l.tag
+}
+
+/**
+ * 获取词元的规范化取值，如"一百二十三"为"123"，没有规范化取值时为空
+ * @return string
+ */
+func (l *Lexeme) GetValue() string {
+	return l.value
+}
+
+/**
+ * 获取时间词元的时段，按TIMEX3记为MO、MI、AF、EV、NI，如"周五晚上"为"EV"，没有时为空
+ * @return string
+ */
+func (l *Lexeme) GetPeriod() string {
+	return l.period
+}
+
+/**
+ * 获取词元在输出序列中的序号
+ * @return int
+ */
+func (l *Lexeme) GetPosition() int {
+	return l.position
+}
+
 func (l *Lexeme) GetTypeString() string {
-	switch l.lexemeType {
-	case LEXEME_TYPE_ENGLISH:
-		return "ENGLISH"
-	case LEXEME_TYPE_ARABIC:
-		return "ARABIC"
-	case LEXEME_TYPE_LETTER:
-		return "LETTER"
-	case LEXEME_TYPE_CNWORD:
-		return "CN_WORD"
-	case LEXEME_TYPE_CNCHAR:
-		return "CN_CHAR"
-	case LEXEME_TYPE_OTHER_CJK:
-		return "OTHER_CJK"
-	case LEXEME_TYPE_COUNT:
-		return "COUNT"
-	case LEXEME_TYPE_CNUM:
-		return "TYPE_CNUM"
-	case LEXEME_TYPE_CQUAN:
-		return "TYPE_CQUAN"
-	default:
-		return "UNKNOWN"
-	}
+	return l.lexemeType.String()
+}
+
+/**
+ * 获取词元类型
+ * @return LexemeType
+ */
+func (l *Lexeme) GetType() LexemeType {
+	return l.lexemeType
 }
 
 /**
@@ -151,7 +287,7 @@ func (l *Lexeme) GetTypeString() string {
  * @return boolean 词元是否成功合并
  */
 
-func (l *Lexeme) append(o *Lexeme, lexemeType int) bool {
+func (l *Lexeme) append(o *Lexeme, lexemeType LexemeType) bool {
 	if o == nil {
 		return false
 	}
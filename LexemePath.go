@@ -11,6 +11,7 @@ import (
 type LexemePath struct {
 	set                               QuickSortSet
 	pathBegin, pathEnd, payloadLength int // 起止位置以及词元链的有效字符长度
+	glued                             int // 虚词粘连数，歧义处理时计算
 }
 
 func NewLexemePath() (l *LexemePath) {
@@ -18,6 +19,17 @@ func NewLexemePath() (l *LexemePath) {
 	return
 }
 
+/**
+ * 返回路径中的全部词元
+ */
+func (lp *LexemePath) lexemes() []*Lexeme {
+	lexemes := make([]*Lexeme, 0, lp.set.size)
+	for c := lp.set.head; c != nil; c = c.next {
+		lexemes = append(lexemes, c.lexeme)
+	}
+	return lexemes
+}
+
 /**
  * 检测词元位置交叉（有歧义的切分）
  * @param lexeme
@@ -97,6 +109,21 @@ func (lp *LexemePath) removeTail() (l *Lexeme) {
 	return
 }
 
+/**
+ * 统计首字或尾字为虚词的多字词元个数
+ * @param functionChar 判断指定位置的字是否为虚词
+ */
+func (lp *LexemePath) countGlued(functionChar func(index int) bool) int {
+	count := 0
+	for c := lp.set.head; c != nil && c.lexeme != nil; c = c.next {
+		l := c.lexeme
+		if l.length > 1 && (functionChar(l.begin) || functionChar(l.begin+l.length-1)) {
+			count++
+		}
+	}
+	return count
+}
+
 /**
  * 获取LexemePath的路径长度
  * @return
@@ -157,6 +184,12 @@ func (lp *LexemePath) compare(nlp *LexemePath) int {
 	if lp.payloadLength < nlp.payloadLength {
 		return 1
 	}
+	if lp.glued < nlp.glued {
+		return -1
+	}
+	if lp.glued > nlp.glued {
+		return 1
+	}
 	if lp.set.size < nlp.set.size {
 		return -1
 	}
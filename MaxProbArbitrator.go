@@ -0,0 +1,51 @@
+package ikgo
+
+import "math"
+
+/**
+ * 最大概率歧义处理策略
+ * 以交叉路径中的词元为边构建有向无环图，每个位置另有一条单字边，
+ * 边的权重为词的对数概率log(freq/totalFreq)，通过动态规划选出概率最大的切分
+ * 词典未给出词频的词按WORD_FREQ_DEFAULT计，不在词典中的单字同样按WORD_FREQ_DEFAULT计
+ */
+type MaxProbArbitrator struct {
+}
+
+func (a MaxProbArbitrator) Arbitrate(path *CrossPath) []*Lexeme {
+	begin := path.path.pathBegin
+	n := path.path.pathEnd - begin
+	logTotal := math.Log(float64(max(path.TotalFreq(), 1)))
+
+	//按起始位置分组的候选词元
+	edges := make([][]*Lexeme, n)
+	for _, l := range path.Lexemes() {
+		edges[l.begin-begin] = append(edges[l.begin-begin], l)
+	}
+
+	//route[i]为从位置i到路径末尾的最大对数概率，choice[i]为位置i上选中的词元，nil表示单字
+	route := make([]float64, n+1)
+	choice := make([]*Lexeme, n)
+	unknown := math.Log(WORD_FREQ_DEFAULT) - logTotal
+	for i := n - 1; i >= 0; i-- {
+		route[i] = unknown + route[i+1]
+		for _, l := range edges[i] {
+			score := math.Log(float64(wordFreq(l.freq))) - logTotal + route[i+l.length]
+			//概率相同时选择较长的词元，词元按长度降序排列
+			if score > route[i] || (choice[i] == nil && score == route[i]) {
+				route[i] = score
+				choice[i] = l
+			}
+		}
+	}
+
+	chosen := []*Lexeme{}
+	for i := 0; i < n; {
+		if l := choice[i]; l != nil {
+			chosen = append(chosen, l)
+			i += l.length
+		} else {
+			i++
+		}
+	}
+	return chosen
+}
@@ -0,0 +1,249 @@
+package ikgo
+
+import "time"
+
+/**
+ * 分词器选项
+ */
+type segmentOptions struct {
+	useSmart         bool
+	dict             *Dictionary
+	useStopwords     bool
+	usePersonNames   bool
+	usePlaceOrgNames bool
+	useSubTokens     bool
+	quantifierPolicy QuantifierPolicy
+	useNumeralFilter bool
+	useTemporal      bool
+	referenceTime    time.Time
+	bufferSize       int
+	newSegmenters    []func() ISegmenter
+	newCustom        []func() Segmenter
+	arbitrator       Arbitrator
+	useHMM           bool
+	hmm              *HMMModel
+}
+
+type Option func(*segmentOptions)
+
+/**
+ * 分词模式
+ */
+type SegmentMode int
+
+const (
+	SEGMENT_MODE_MAX_WORD SegmentMode = iota // 细粒度切分，输出全部词元
+	SEGMENT_MODE_SMART                       // IK智能分词，启发式歧义处理
+	SEGMENT_MODE_MAX_PROB                    // 智能分词，按词频选出概率最大的切分
+)
+
+/**
+ * 设置分词模式，等价于WithSmart与WithArbitrator的组合
+ */
+func WithMode(mode SegmentMode) Option {
+	return func(o *segmentOptions) {
+		switch mode {
+		case SEGMENT_MODE_SMART:
+			o.useSmart = true
+			o.arbitrator = IKArbitrator{}
+		case SEGMENT_MODE_MAX_PROB:
+			o.useSmart = true
+			o.arbitrator = MaxProbArbitrator{}
+		default:
+			o.useSmart = false
+		}
+	}
+}
+
+/**
+ * 是否使用智能分词，默认为细粒度分词
+ */
+func WithSmart(useSmart bool) Option {
+	return func(o *segmentOptions) {
+		o.useSmart = useSmart
+	}
+}
+
+/**
+ * 使用指定的词典实例，默认使用默认词典
+ */
+func WithDictionary(dict *Dictionary) Option {
+	return func(o *segmentOptions) {
+		o.dict = dict
+	}
+}
+
+/**
+ * 是否过滤停止词，默认过滤
+ */
+func WithStopwords(useStopwords bool) Option {
+	return func(o *segmentOptions) {
+		o.useStopwords = useStopwords
+	}
+}
+
+/**
+ * 是否识别人名，默认不识别，与原IK分词结果一致
+ * 姓氏加名字常用字组成的候选人名以LEXEME_TYPE_PERSON参与歧义处理
+ */
+func WithPersonNames(usePersonNames bool) Option {
+	return func(o *segmentOptions) {
+		o.usePersonNames = usePersonNames
+	}
+}
+
+/**
+ * 是否识别地名、机构名，默认不识别，与原IK分词结果一致
+ * 后缀词典中的后缀与其前面的文字组成LEXEME_TYPE_PLACE、LEXEME_TYPE_ORG词元参与歧义处理
+ */
+func WithPlaceOrgNames(usePlaceOrgNames bool) Option {
+	return func(o *segmentOptions) {
+		o.usePlaceOrgNames = usePlaceOrgNames
+	}
+}
+
+/**
+ * 是否在地名、机构名等实体之后输出其组成部分，默认不输出
+ * 如"百姓网络科技有限公司"之后依次输出"百姓"、"网络"、"科技"、"有限公司"，便于建立索引
+ * 细粒度分词已输出全部词元，该选项主要用于智能分词
+ */
+func WithSubTokens(useSubTokens bool) Option {
+	return func(o *segmentOptions) {
+		o.useSubTokens = useSubTokens
+	}
+}
+
+/**
+ * 数词与量词的合并策略，默认为QUANTIFIER_POLICY_IK
+ */
+func WithQuantifierPolicy(policy QuantifierPolicy) Option {
+	return func(o *segmentOptions) {
+		o.quantifierPolicy = policy
+	}
+}
+
+/**
+ * 是否将中文数词的词元文本替换为阿拉伯数字，默认不替换
+ * 替换后"一百二十三"与"123"、"三个"与"3个"输出相同的文本，便于建立索引；词元位置不变
+ * 无论是否替换，都可以通过Lexeme.GetValue或Token.Value取得数词的值
+ */
+func WithNumeralFilter(useNumeralFilter bool) Option {
+	return func(o *segmentOptions) {
+		o.useNumeralFilter = useNumeralFilter
+	}
+}
+
+/**
+ * 是否识别日期、时刻及时长，默认不识别，与原IK分词结果一致
+ * 识别出的表达式输出为LEXEME_TYPE_DATE、LEXEME_TYPE_TIME、LEXEME_TYPE_DURATION词元，取值见Lexeme.GetValue
+ */
+func WithTemporal(useTemporal bool) Option {
+	return func(o *segmentOptions) {
+		o.useTemporal = useTemporal
+	}
+}
+
+/**
+ * 计算"明天"、"下周五"等相对日期的参照时间，默认为创建分词器时的time.Now()
+ * 使用默认值时同一文本在不同时间的取值不同，需要可重复的结果（如测试、重建索引）时应指定
+ */
+func WithReferenceTime(t time.Time) Option {
+	return func(o *segmentOptions) {
+		o.referenceTime = t
+	}
+}
+
+/**
+ * 字符缓冲区大小，默认为AC_BUFF_SIZE
+ * 缓冲区应明显大于最长的词，否则跨越缓冲区末尾的词会被截断
+ */
+func WithBufferSize(size int) Option {
+	return func(o *segmentOptions) {
+		o.bufferSize = size
+	}
+}
+
+/**
+ * 指定子分词器及其执行顺序，默认为字母、量词、中文、人名、地名机构名、时间六个子分词器
+ * 子分词器带有状态，因此传入的是构造函数，每个IKSegmenter各自创建实例
+ */
+func WithSegmenters(newSegmenters ...func() ISegmenter) Option {
+	return func(o *segmentOptions) {
+		o.newSegmenters = newSegmenters
+	}
+}
+
+/**
+ * 注册自定义子分词器，在内置子分词器之后执行，可以多次使用
+ * 与WithSegmenters一样传入构造函数，每个IKSegmenter各自创建实例
+ */
+func WithCustomSegmenters(newSegmenters ...func() Segmenter) Option {
+	return func(o *segmentOptions) {
+		o.newCustom = append(o.newCustom, newSegmenters...)
+	}
+}
+
+/**
+ * 智能分词模式下使用的歧义处理策略，默认为IKArbitrator
+ */
+func WithArbitrator(arbitrator Arbitrator) Option {
+	return func(o *segmentOptions) {
+		o.arbitrator = arbitrator
+	}
+}
+
+/**
+ * 是否使用隐马尔可夫模型识别未登录词，默认不使用
+ * 启用后，连续的未成词中文单字交给模型重新切分，多字词以LEXEME_TYPE_OOV输出
+ * 未通过WithHMMModel指定模型时，使用由当前主词典训练的模型
+ */
+func WithHMM(useHMM bool) Option {
+	return func(o *segmentOptions) {
+		o.useHMM = useHMM
+	}
+}
+
+/**
+ * 使用指定的模型识别未登录词，同时启用未登录词识别
+ */
+func WithHMMModel(model *HMMModel) Option {
+	return func(o *segmentOptions) {
+		o.useHMM = model != nil
+		o.hmm = model
+	}
+}
+
+// 默认的子分词器
+var defaultSegmenters = []func() ISegmenter{
+	func() ISegmenter { return NewLetterSegmenter() },
+	func() ISegmenter { return NewCN_QuantifierSegmenter() },
+	func() ISegmenter { return NewCJKSegmenter() },
+	func() ISegmenter { return NewCN_PersonSegmenter() },
+	func() ISegmenter { return NewCN_SuffixSegmenter() },
+	func() ISegmenter { return NewCN_TimeSegmenter() },
+}
+
+func newSegmentOptions(opts []Option) *segmentOptions {
+	o := &segmentOptions{
+		useStopwords:  true,
+		bufferSize:    AC_BUFF_SIZE,
+		newSegmenters: defaultSegmenters,
+		arbitrator:    IKArbitrator{},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.dict == nil {
+		o.dict = defaultDictionary()
+	}
+	if o.arbitrator == nil {
+		o.arbitrator = IKArbitrator{}
+	}
+	if o.referenceTime.IsZero() {
+		o.referenceTime = time.Now()
+	}
+	if o.bufferSize <= 0 {
+		o.bufferSize = AC_BUFF_SIZE
+	}
+	return o
+}
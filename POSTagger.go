@@ -0,0 +1,113 @@
+package ikgo
+
+import "sync"
+
+/**
+ * 词性标注
+ * 词典词使用词典中给出的词性，标注体系与词典一致（如jieba使用的ICTCLAS标注集）
+ * 词典未给出词性的未登录词、字母及数字词元，按规则推测词性
+ */
+const (
+	POS_TAG_NOUN               = "n"   //名词
+	POS_TAG_NUMERAL            = "m"   //数词
+	POS_TAG_QUANTIFIER         = "q"   //量词
+	POS_TAG_NUMERAL_QUANTIFIER = "mq"  //数量词
+	POS_TAG_PERSON             = "nr"  //人名
+	POS_TAG_PLACE              = "ns"  //地名
+	POS_TAG_ORG                = "nt"  //机构名
+	POS_TAG_TIME               = "t"   //时间词
+	POS_TAG_ENGLISH            = "eng" //英文及字母数字混合
+	POS_TAG_UNKNOWN            = "x"   //非语素字
+)
+
+/**
+ * 为没有词性的词元推测词性
+ * 词典词未给出词性时保持为空
+ * @param l
+ */
+func (ac *AnalyzeContext) tagLexeme(l *Lexeme) {
+	if l.tag != "" {
+		return
+	}
+	switch l.lexemeType {
+	case LEXEME_TYPE_ENGLISH, LEXEME_TYPE_LETTER:
+		l.tag = POS_TAG_ENGLISH
+	case LEXEME_TYPE_ARABIC, LEXEME_TYPE_CNUM:
+		l.tag = POS_TAG_NUMERAL
+	case LEXEME_TYPE_COUNT:
+		l.tag = POS_TAG_QUANTIFIER
+	case LEXEME_TYPE_CQUAN:
+		l.tag = POS_TAG_NUMERAL_QUANTIFIER
+	case LEXEME_TYPE_PERSON:
+		l.tag = POS_TAG_PERSON
+	case LEXEME_TYPE_PLACE:
+		l.tag = POS_TAG_PLACE
+	case LEXEME_TYPE_ORG:
+		l.tag = POS_TAG_ORG
+	case LEXEME_TYPE_DATE, LEXEME_TYPE_TIME, LEXEME_TYPE_DURATION:
+		l.tag = POS_TAG_TIME
+	case LEXEME_TYPE_CNCHAR, LEXEME_TYPE_OTHER_CJK:
+		l.tag = POS_TAG_UNKNOWN
+	case LEXEME_TYPE_OOV:
+		l.tag = ac.oovTag(l)
+	}
+}
+
+/**
+ * 未登录词的词性取词典中以同一个字结尾的词最常见的词性，如"东坡肉"按"牛肉"、"猪肉"等词取名词
+ * 汉语复合词的中心语一般在后，词典中没有可参考的词时按名词处理
+ * @param l
+ */
+func (ac *AnalyzeContext) oovTag(l *Lexeme) string {
+	if tag, exists := ac.dicts.tailTagMap()[ac.segmentBuff[l.begin+l.length-1]]; exists {
+		return tag
+	}
+	return POS_TAG_NOUN
+}
+
+/**
+ * 词尾字到词性的映射，每个词典快照在第一次使用时统计一次
+ */
+type lazyTailTags struct {
+	once sync.Once
+	tags map[rune]string
+}
+
+func (set *dictSet) tailTagMap() map[rune]string {
+	set.tailTags.once.Do(func() {
+		set.tailTags.tags = countTailTags(set.mainDict)
+	})
+	return set.tailTags.tags
+}
+
+/**
+ * 按词频统计每个词尾字上各词性的出现次数，取次数最多的词性
+ */
+func countTailTags(root *DictSegment) map[rune]string {
+	counts := make(map[rune]map[string]int64)
+	var walk func(ds *DictSegment)
+	walk = func(ds *DictSegment) {
+		for _, child := range ds.sortedChildren() {
+			if child.nodeState == 1 && child.tag != "" {
+				if counts[child.nodeChar] == nil {
+					counts[child.nodeChar] = make(map[string]int64)
+				}
+				counts[child.nodeChar][child.tag] += int64(wordFreq(child.freq))
+			}
+			walk(child)
+		}
+	}
+	walk(root)
+
+	tags := make(map[rune]string, len(counts))
+	for c, tagCounts := range counts {
+		best := ""
+		for tag, count := range tagCounts {
+			if best == "" || count > tagCounts[best] || (count == tagCounts[best] && tag < best) {
+				best = tag
+			}
+		}
+		tags[c] = best
+	}
+	return tags
+}
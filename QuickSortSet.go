@@ -120,3 +120,28 @@ func (q *QuickSortSet) pollLast() (l *Lexeme) {
 	l = nil
 	return
 }
+
+/**
+ * 移除与指定词元位置相同的词元
+ * @return 是否移除
+ */
+func (q *QuickSortSet) removeLexeme(l *Lexeme) bool {
+	for c := q.tail; c != nil; c = c.prev {
+		if c.lexeme.compare(l) != 0 {
+			continue
+		}
+		if c.prev != nil {
+			c.prev.next = c.next
+		} else {
+			q.head = c.next
+		}
+		if c.next != nil {
+			c.next.prev = c.prev
+		} else {
+			q.tail = c.prev
+		}
+		q.size--
+		return true
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package ikgo
+
+import "iter"
+
+/**
+ * 分词结果
+ * Start、End为词元在文本中的字符（rune）位置，End不包含在词元内
+ * ByteStart、ByteEnd为字节位置，UTF16Start、UTF16End为UTF-16码元位置，同样不包含End
+ * Position为词元在输出序列中的序号，Tag为词性，Value为规范化取值
+ */
+type Token struct {
+	Text       string     `json:"text"`
+	Start      int        `json:"start"`
+	End        int        `json:"end"`
+	ByteStart  int        `json:"byte_start"`
+	ByteEnd    int        `json:"byte_end"`
+	UTF16Start int        `json:"utf16_start"`
+	UTF16End   int        `json:"utf16_end"`
+	Type       LexemeType `json:"type"`
+	Position   int        `json:"position"`
+	Tag        string     `json:"tag,omitempty"`
+	Value      string     `json:"value,omitempty"`
+	Period     string     `json:"period,omitempty"`
+}
+
+/**
+ * 转换为Token
+ */
+func (l *Lexeme) Token() Token {
+	return Token{
+		Text:       l.GetText(),
+		Start:      l.GetBeginPosition(),
+		End:        l.GetEndPosition(),
+		ByteStart:  l.byteBegin,
+		ByteEnd:    l.byteEnd,
+		UTF16Start: l.utf16Begin,
+		UTF16End:   l.utf16End,
+		Type:       l.lexemeType,
+		Position:   l.position,
+		Tag:        l.tag,
+		Value:      l.value,
+		Period:     l.period,
+	}
+}
+
+/**
+ * 对文本分词，返回全部词元
+ * @param text 待分词文本
+ * @param opts 分词选项
+ */
+func Segment(text string, opts ...Option) []Token {
+	s := NewIKSegmenter(text, opts...)
+	tokens := []Token{}
+	for token := range s.Tokens() {
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+/**
+ * 对文本分词，只返回词元文本
+ * @param text 待分词文本
+ * @param opts 分词选项
+ */
+func SegmentStrings(text string, opts ...Option) []string {
+	tokens := Segment(text, opts...)
+	words := make([]string, len(tokens))
+	for i, token := range tokens {
+		words[i] = token.Text
+	}
+	return words
+}
+
+/**
+ * 返回剩余词元的迭代器，可直接用于range循环
+ * 迭代结束后可通过Err检查读取错误
+ */
+func (s *IKSegmenter) Tokens() iter.Seq[Token] {
+	return func(yield func(Token) bool) {
+		for l := s.Next(); l != nil; l = s.Next() {
+			if !yield(l.Token()) {
+				return
+			}
+		}
+	}
+}
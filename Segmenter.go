@@ -0,0 +1,145 @@
+package ikgo
+
+import "fmt"
+
+/**
+ * 自定义子分词器接口
+ * 与内置的LetterSegmenter、CN_QuantifierSegmenter、CJKSegmenter一样，
+ * 在每个字符位置上被调用一次，输出的词元与内置子分词器的结果一起参与歧义处理
+ */
+type Segmenter interface {
+	/**
+	 * 分析当前指针位置的字符，可能输出词元
+	 * @param ctx 分词上下文的只读视图
+	 */
+	Analyze(ctx *SegmentContext)
+
+	/**
+	 * 重置子分词器状态，每轮缓冲区处理完毕后调用
+	 */
+	Reset()
+}
+
+/**
+ * 提供给自定义子分词器的分词上下文视图
+ * 位置均为字符在当前缓冲区中的下标，Offset返回缓冲区起始字符在全文中的位置
+ */
+type SegmentContext struct {
+	ac   *AnalyzeContext
+	name string //锁定缓冲区时使用的名称
+}
+
+/**
+ * 当前指针位置
+ */
+func (c *SegmentContext) Cursor() int {
+	return c.ac.cursor
+}
+
+/**
+ * 缓冲区中有效字符数
+ */
+func (c *SegmentContext) Available() int {
+	return c.ac.available
+}
+
+/**
+ * 当前缓冲区起始字符在全文中的位置
+ */
+func (c *SegmentContext) Offset() int {
+	return c.ac.bufOffset
+}
+
+/**
+ * 返回缓冲区中指定位置的字符
+ */
+func (c *SegmentContext) Rune(index int) rune {
+	return c.ac.segmentBuff[index]
+}
+
+/**
+ * 返回缓冲区中指定位置的字符类型，CHAR_USELESS、CHAR_ARABIC等
+ */
+func (c *SegmentContext) CharType(index int) int {
+	if index <= c.ac.cursor {
+		return c.ac.charType[index]
+	}
+	return identifyCharType(c.ac.segmentBuff[index])
+}
+
+/**
+ * 当前指针位置的字符
+ */
+func (c *SegmentContext) CurrentRune() rune {
+	return c.Rune(c.ac.cursor)
+}
+
+/**
+ * 当前指针位置的字符类型
+ */
+func (c *SegmentContext) CurrentCharType() int {
+	return c.ac.charType[c.ac.cursor]
+}
+
+/**
+ * 指针是否已经到达缓冲区末尾，此时应输出尚未结束的词元
+ */
+func (c *SegmentContext) IsBufferConsumed() bool {
+	return c.ac.isBufferConsumed()
+}
+
+/**
+ * 输出一个词元
+ * @param begin 词元在缓冲区中的起始位置
+ * @param length 词元长度
+ * @param lexemeType 词元类型
+ * @return 位置越界时不输出，返回false
+ */
+func (c *SegmentContext) AddLexeme(begin, length int, lexemeType LexemeType) bool {
+	if begin < 0 || length <= 0 || begin+length > c.ac.available {
+		return false
+	}
+	c.ac.addLexeme(NewLexeme(c.ac.bufOffset, begin, length, lexemeType))
+	return true
+}
+
+/**
+ * 锁定缓冲区，正在识别的词元尚未结束时调用，避免缓冲区在词元中间被移位
+ */
+func (c *SegmentContext) LockBuffer() {
+	c.ac.lockBuffer(c.name)
+}
+
+/**
+ * 释放对缓冲区的锁定
+ */
+func (c *SegmentContext) UnlockBuffer() {
+	c.ac.unlockBuffer(c.name)
+}
+
+/**
+ * 将自定义子分词器适配为ISegmenter
+ */
+type customSegmenter struct {
+	segmenter Segmenter
+	name      string
+	ctx       *SegmentContext
+}
+
+func newCustomSegmenter(segmenter Segmenter, index int) *customSegmenter {
+	return &customSegmenter{
+		segmenter: segmenter,
+		name:      fmt.Sprintf("CUSTOM_SEGMENTER_%d", index),
+	}
+}
+
+func (s *customSegmenter) analyze(context *AnalyzeContext) {
+	if s.ctx == nil || s.ctx.ac != context {
+		s.ctx = &SegmentContext{ac: context, name: s.name}
+	}
+	s.segmenter.Analyze(s.ctx)
+}
+
+func (s *customSegmenter) reset() {
+	s.segmenter.Reset()
+}
@@ -0,0 +1,78 @@
+/**
+ * ikgo命令行工具
+ *
+ *	ikgo dict compile -dir <词典目录> -o <输出文件> [-allow-missing]
+ *
+ * 将词典目录（IKAnalyzer.cfg.xml及各词典文件）编译为二进制词典，
+ * 运行时通过ikgo.LoadCompiledDictionary快速加载
+ * -allow-missing 缺失的可选词典只作为警告输出
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/baixingdong/ikgo"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: ikgo dict compile -dir <dict dir> -o <output file> [-allow-missing]")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 3 || os.Args[1] != "dict" || os.Args[2] != "compile" {
+		usage()
+	}
+	if err := compile(os.Args[3:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func compile(args []string) error {
+	fs := flag.NewFlagSet("dict compile", flag.ExitOnError)
+	dir := fs.String("dir", "", "dictionary directory containing IKAnalyzer.cfg.xml and *.dic files")
+	out := fs.String("o", "", "output file")
+	lenient := fs.Bool("allow-missing", false, "treat missing optional dictionaries as warnings")
+	fs.Parse(args)
+	if *dir == "" || *out == "" {
+		usage()
+	}
+
+	opts := []ikgo.LoadOption{}
+	if *lenient {
+		opts = append(opts, ikgo.WithMissingOptionalAsWarning())
+	}
+	start := time.Now()
+	d, err := ikgo.LoadDictionary(*dir, opts...)
+	if err != nil {
+		return err
+	}
+	for _, w := range d.Report().Warnings {
+		fmt.Fprintln(os.Stderr, "warning:", w)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err = d.WriteCompiled(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	for _, stat := range d.Report().Files {
+		if stat.Err == nil {
+			fmt.Printf("%-24s %d words\n", stat.Name, stat.Words)
+		}
+	}
+	fmt.Printf("compiled %s in %v\n", *out, time.Since(start))
+	return nil
+}
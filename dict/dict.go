@@ -0,0 +1,61 @@
+/**
+ * 内置的默认IK词典
+ *
+ * 以匿名方式导入即可将内置词典设置为ikgo的默认词典：
+ *
+ *	import _ "github.com/baixingdong/ikgo/dict"
+ *
+ * 内置词典包含主词典、姓氏、量词、后缀、介词、停止词六个文件，
+ * 是手工挑选的示例词条，不是由IK的词典文件裁剪而来，只适合试用；后缀词典带有ns、nt、nr词性
+ * 生产环境应通过ikgo.LoadDictionary加载IK发布的完整词典
+ */
+package dict
+
+import (
+	"embed"
+	"io/fs"
+	"sync"
+
+	"github.com/baixingdong/ikgo"
+)
+
+//go:embed *.dic
+var files embed.FS
+
+var (
+	defaultOnce sync.Once
+	defaultDict *ikgo.Dictionary
+)
+
+func init() {
+	ikgo.SetDefaultDictionary(Default())
+}
+
+/**
+ * 返回内置词典文件系统，可与ikgo.LoadDictionaryFS配合使用
+ */
+func FS() fs.FS {
+	return files
+}
+
+/**
+ * 加载一套新的内置词典实例
+ */
+func Load(opts ...ikgo.LoadOption) (*ikgo.Dictionary, error) {
+	return ikgo.LoadDictionaryFS(files, opts...)
+}
+
+/**
+ * 返回共享的内置词典实例，只加载一次
+ */
+func Default() *ikgo.Dictionary {
+	defaultOnce.Do(func() {
+		d, err := Load()
+		if err != nil {
+			// 内置词典随二进制一同发布，加载失败说明构建有误
+			panic(err)
+		}
+		defaultDict = d
+	})
+	return defaultDict
+}
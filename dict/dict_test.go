@@ -0,0 +1,70 @@
+package dict_test
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/baixingdong/ikgo"
+	"github.com/baixingdong/ikgo/dict"
+)
+
+func TestLoad(t *testing.T) {
+	d, err := dict.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := d.Report().Files
+	if len(files) != 6 {
+		t.Fatalf("expected 6 dictionary files, got %d", len(files))
+	}
+	dictTypes := map[string]int{
+		ikgo.PATH_DIC_MAIN:       ikgo.DICT_TYPE_MAIN,
+		ikgo.PATH_DIC_SURNAME:    ikgo.DICT_TYPE_SURNAME,
+		ikgo.PATH_DIC_QUANTIFIER: ikgo.DICT_TYPE_QUANTIFIER,
+		ikgo.PATH_DIC_SUFFIX:     ikgo.DICT_TYPE_SUFFIX,
+		ikgo.PATH_DIC_PREP:       ikgo.DICT_TYPE_PREP,
+		ikgo.PATH_DIC_STOP:       ikgo.DICT_TYPE_STOP,
+	}
+	for _, f := range files {
+		if f.Words == 0 {
+			t.Errorf("%s is empty", f.Name)
+		}
+		content, err := fs.ReadFile(dict.FS(), f.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			//每行第一列为词，其后可以是词频
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			word := fields[0]
+			if !d.ContainsDictWord(dictTypes[f.Name], word) {
+				t.Errorf("%s: missing %s", f.Name, word)
+			}
+		}
+	}
+}
+
+func TestDefaultSegmenter(t *testing.T) {
+	segmenter := ikgo.NewIKSegmenter("测试分词实例", ikgo.WithSmart(true))
+	words := []string{}
+	for l := segmenter.Next(); l != nil; l = segmenter.Next() {
+		words = append(words, l.GetText())
+	}
+	if got := strings.Join(words, "/"); got != "测试/分词/实例" {
+		t.Errorf("got %s", got)
+	}
+	//后缀词典带有词性，可以识别地名
+	var places []string
+	for _, tok := range ikgo.Segment("北京朝阳区望京附近", ikgo.WithSmart(true), ikgo.WithPlaceOrgNames(true)) {
+		if tok.Type == ikgo.LEXEME_TYPE_PLACE {
+			places = append(places, tok.Text)
+		}
+	}
+	if strings.Join(places, "/") != "北京朝阳区" {
+		t.Errorf("places: got %v", places)
+	}
+}
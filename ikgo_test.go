@@ -1,15 +1,31 @@
 package ikgo
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"testing/iotest"
+	"time"
+	"unicode/utf16"
 )
 
 func TestIkgo(t *testing.T) {
-	InitDict("/data/baixing/dict/ik/analysis-ik", true)
+	SetDefaultDictionary(testDictionary(t))
 	text := "测试分词实例"
 
-	segmenter := NewIKSegmenter(text, true)
+	segmenter := NewIKSegmenter(text, WithSmart(true))
 	for {
 		lexme := segmenter.Next()
 		if lexme == nil {
@@ -18,3 +34,1477 @@ func TestIkgo(t *testing.T) {
 		fmt.Printf("%+v\n", lexme)
 	}
 }
+
+/**
+ * 加载dict子包中的内置词典
+ */
+func testDictionary(t testing.TB, opts ...LoadOption) *Dictionary {
+	d, err := LoadDictionaryFS(os.DirFS("dict"), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+/**
+ * 在临时目录中生成词典文件
+ */
+func writeDictDir(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func segmentTexts(s *IKSegmenter) []string {
+	words := []string{}
+	for l := s.Next(); l != nil; l = s.Next() {
+		words = append(words, l.GetText())
+	}
+	return words
+}
+
+func TestDictionaryInstances(t *testing.T) {
+	d1 := NewDictionary(writeDictDir(t, map[string]string{
+		PATH_DIC_MAIN: "中华\n人民\n共和国\n",
+	}))
+	d2 := NewDictionary(writeDictDir(t, map[string]string{
+		PATH_DIC_MAIN: "中华人民共和国\n",
+		PATH_DIC_STOP: "的\n",
+	}))
+
+	text := "中华人民共和国的"
+	got1 := strings.Join(segmentTexts(NewIKSegmenter(text, WithSmart(true), WithDictionary(d1))), "/")
+	if got1 != "中华/人民/共和国/的" {
+		t.Errorf("d1: got %s", got1)
+	}
+	got2 := strings.Join(segmentTexts(NewIKSegmenter(text, WithSmart(true), WithDictionary(d2))), "/")
+	if got2 != "中华人民共和国" {
+		t.Errorf("d2: got %s", got2)
+	}
+}
+
+func TestLoadDictionaryErrors(t *testing.T) {
+	full := map[string]string{
+		PATH_DIC_MAIN:       "中华\n人民\n\n共和国\n",
+		PATH_DIC_QUANTIFIER: "个\n",
+		PATH_DIC_SURNAME:    "张\n",
+		PATH_DIC_SUFFIX:     "市\n",
+		PATH_DIC_PREP:       "在\n",
+		PATH_DIC_STOP:       "的\n",
+		FILE_NAME: `<?xml version="1.0" encoding="UTF-8"?>
+<properties><entry key="ext_dict">ext.dic;</entry></properties>`,
+		"ext.dic": "分词\n",
+	}
+
+	d, err := LoadDictionary(writeDictDir(t, full))
+	if err != nil {
+		t.Fatal(err)
+	}
+	words := map[string]int{}
+	for _, f := range d.Report().Files {
+		words[f.Name] = f.Words
+	}
+	if words[PATH_DIC_MAIN] != 3 || words["ext.dic"] != 1 {
+		t.Errorf("unexpected word counts %v", words)
+	}
+
+	delete(full, PATH_DIC_MAIN)
+	if _, err = LoadDictionary(writeDictDir(t, full)); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing main.dic: got %v", err)
+	}
+
+	full[PATH_DIC_MAIN] = "中华\n"
+	delete(full, "ext.dic")
+	delete(full, PATH_DIC_SURNAME)
+	if _, err = LoadDictionary(writeDictDir(t, full)); err == nil {
+		t.Error("missing ext_dict entry should fail")
+	}
+	d, err = LoadDictionary(writeDictDir(t, full), WithMissingOptionalAsWarning())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Report().Warnings) != 2 {
+		t.Errorf("expected 2 warnings, got %v", d.Report().Warnings)
+	}
+
+	full[FILE_NAME] = "<properties><entry key="
+	var le *DictLoadError
+	if _, err = LoadDictionary(writeDictDir(t, full), WithMissingOptionalAsWarning()); !errors.As(err, &le) || le.File != FILE_NAME {
+		t.Errorf("malformed config: got %v", err)
+	}
+}
+
+func TestLoadDictionaryFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		PATH_DIC_MAIN:       {Data: []byte("中华\n人民\n")},
+		PATH_DIC_QUANTIFIER: {Data: []byte("个\n")},
+		"custom/ext.dic":    {Data: []byte("共和国\r\n")},
+		FILE_NAME: {Data: []byte(`<properties>
+<entry key="ext_dict">./custom/ext.dic</entry>
+</properties>`)},
+	}
+	d, err := LoadDictionaryFS(fsys, WithMissingOptionalAsWarning())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(segmentTexts(NewIKSegmenter("中华人民共和国", WithSmart(true), WithDictionary(d))), "/")
+	if got != "中华/人民/共和国" {
+		t.Errorf("got %s", got)
+	}
+
+	e := NewEmptyDictionary()
+	n, err := e.LoadWords(DICT_TYPE_MAIN, strings.NewReader("  分词 \n\n实例\n"))
+	if err != nil || n != 2 {
+		t.Fatalf("LoadWords: %d, %v", n, err)
+	}
+	got = strings.Join(segmentTexts(NewIKSegmenter("测试分词实例", WithSmart(true), WithDictionary(e))), "/")
+	if got != "测/试/分词/实例" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestDictionaryReload(t *testing.T) {
+	dir := writeDictDir(t, map[string]string{
+		PATH_DIC_MAIN:       "分词\n",
+		PATH_DIC_QUANTIFIER: "个\n",
+	})
+	d, err := LoadDictionary(dir, WithMissingOptionalAsWarning())
+	if err != nil {
+		t.Fatal(err)
+	}
+	segment := func() string {
+		return strings.Join(segmentTexts(NewIKSegmenter("测试分词", WithSmart(true), WithDictionary(d))), "/")
+	}
+	if got := segment(); got != "测/试/分词" {
+		t.Fatalf("got %s", got)
+	}
+
+	reloaded := make(chan error, 1)
+	stop, err := d.Watch(10*time.Millisecond, func(err error) {
+		reloaded <- err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	mainPath := filepath.Join(dir, PATH_DIC_MAIN)
+	if err = os.WriteFile(mainPath, []byte("分词\n测试\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(mainPath, future, future)
+
+	select {
+	case err = <-reloaded:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("dictionary was not reloaded")
+	}
+	if got := segment(); got != "测试/分词" {
+		t.Errorf("after reload: got %s", got)
+	}
+
+	//加载失败时保留原有词典
+	os.Remove(mainPath)
+	if err = d.Reload(); err == nil {
+		t.Error("reload without main.dic should fail")
+	}
+	if got := segment(); got != "测试/分词" {
+		t.Errorf("after failed reload: got %s", got)
+	}
+}
+
+func TestRemoteExtDict(t *testing.T) {
+	var mu sync.Mutex
+	words, etag := "测试\n", `"v1"`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, words)
+		}
+	}))
+	defer server.Close()
+
+	fsys := fstest.MapFS{
+		PATH_DIC_MAIN:       {Data: []byte("分词\n")},
+		PATH_DIC_QUANTIFIER: {Data: []byte("个\n")},
+		FILE_NAME: {Data: []byte(`<properties>
+<entry key="remote_ext_dict">` + server.URL + `/words.txt</entry>
+</properties>`)},
+	}
+	d, err := LoadDictionaryFS(fsys, WithMissingOptionalAsWarning(), WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	segment := func() string {
+		return strings.Join(segmentTexts(NewIKSegmenter("测试分词实例", WithSmart(true), WithDictionary(d))), "/")
+	}
+	if got := segment(); got != "测试/分词/实/例" {
+		t.Fatalf("got %s", got)
+	}
+
+	changed, err := d.CheckRemote()
+	if err != nil || changed {
+		t.Fatalf("unchanged remote: changed=%v err=%v", changed, err)
+	}
+
+	mu.Lock()
+	words, etag = "测试\n实例\n", `"v2"`
+	mu.Unlock()
+	changed, err = d.CheckRemote()
+	if err != nil || !changed {
+		t.Fatalf("changed remote: changed=%v err=%v", changed, err)
+	}
+	if got := segment(); got != "测试/分词/实例" {
+		t.Errorf("after remote update: got %s", got)
+	}
+}
+
+func TestRemoteExtDictFailure(t *testing.T) {
+	var mu sync.Mutex
+	gets := map[string]int{}
+	etags := map[string]string{"/a.txt": `"a1"`, "/b.txt": `"b1"`}
+	words := map[string]string{"/a.txt": "测试\n", "/b.txt": "实例\n"}
+	broken := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodGet {
+			gets[r.URL.Path]++
+			if broken && r.URL.Path == "/b.txt" {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		}
+		if r.Header.Get("If-None-Match") == etags[r.URL.Path] {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etags[r.URL.Path])
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, words[r.URL.Path])
+		}
+	}))
+	defer server.Close()
+
+	fsys := fstest.MapFS{
+		PATH_DIC_MAIN:       {Data: []byte("分词\n")},
+		PATH_DIC_QUANTIFIER: {Data: []byte("个\n")},
+		FILE_NAME: {Data: []byte(`<properties>
+<entry key="remote_ext_dict">` + server.URL + `/a.txt;` + server.URL + `/b.txt</entry>
+</properties>`)},
+	}
+	d, err := LoadDictionaryFS(fsys, WithMissingOptionalAsWarning(), WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	segment := func() string {
+		return strings.Join(segmentTexts(NewIKSegmenter("测试分词实例公司", WithSmart(true), WithDictionary(d))), "/")
+	}
+	if got := segment(); got != "测试/分词/实例/公/司" {
+		t.Fatalf("got %s", got)
+	}
+
+	//a.txt更新时b.txt不重新下载；b.txt更新但下载失败时保留原有词条
+	mu.Lock()
+	etags["/a.txt"], words["/a.txt"] = `"a2"`, "测试\n公司\n"
+	etags["/b.txt"], words["/b.txt"] = `"b2"`, "实例\n"
+	broken = true
+	mu.Unlock()
+	changed, err := d.CheckRemote()
+	if err != nil || !changed {
+		t.Fatalf("changed remote: changed=%v err=%v", changed, err)
+	}
+	if got := segment(); got != "测试/分词/实例/公司" {
+		t.Errorf("after failed fetch: got %s", got)
+	}
+	if w := d.Report().Warnings; len(w) == 0 || !strings.Contains(w[len(w)-1].Error(), "b.txt") {
+		t.Errorf("warnings: %v", w)
+	}
+
+	//失败的词典在下次检查时重试，未更新的词典不再下载
+	mu.Lock()
+	broken = false
+	mu.Unlock()
+	if changed, err = d.CheckRemote(); err != nil || !changed {
+		t.Fatalf("retry: changed=%v err=%v", changed, err)
+	}
+	if changed, err = d.CheckRemote(); err != nil || changed {
+		t.Fatalf("unchanged remote: changed=%v err=%v", changed, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if gets["/a.txt"] != 2 || gets["/b.txt"] != 3 {
+		t.Errorf("gets: %v", gets)
+	}
+}
+
+func TestRuntimeWords(t *testing.T) {
+	d, err := LoadDictionaryFS(fstest.MapFS{
+		PATH_DIC_MAIN:       {Data: []byte("分词\n实例\n")},
+		PATH_DIC_QUANTIFIER: {Data: []byte("个\n")},
+	}, WithMissingOptionalAsWarning())
+	if err != nil {
+		t.Fatal(err)
+	}
+	segment := func() string {
+		return strings.Join(segmentTexts(NewIKSegmenter("测试分词实例", WithSmart(true), WithDictionary(d))), "/")
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					segment()
+				}
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		d.AddWords([]string{"测试", fmt.Sprintf("新词%d", i)})
+		d.LoadWords(DICT_TYPE_MAIN, strings.NewReader(fmt.Sprintf("载入%d 10 n\n", i)))
+	}
+	close(stop)
+	wg.Wait()
+
+	if !d.ContainsWord("测试") || !d.ContainsWord("新词49") || d.ContainsWord("测") {
+		t.Error("ContainsWord mismatch after AddWords")
+	}
+	if got := segment(); got != "测试/分词/实例" {
+		t.Errorf("after AddWords: got %s", got)
+	}
+
+	d.DisableWords([]string{"分词"})
+	if got := segment(); got != "测试/分/词/实例" {
+		t.Errorf("after DisableWords: got %s", got)
+	}
+	if err = d.AddDictWords(DICT_TYPE_STOP, []string{"实例"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := segment(); got != "测试/分/词" {
+		t.Errorf("after adding stopword: got %s", got)
+	}
+
+	//重新加载后运行时修改依然有效
+	if err = d.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if !d.ContainsWord("测试") || d.ContainsWord("分词") || !d.ContainsDictWord(DICT_TYPE_STOP, "实例") {
+		t.Error("runtime patches lost after reload")
+	}
+	if l := NewIKSegmenter("载入49", WithDictionary(d)).Next(); l == nil || l.GetText() != "载入49" || l.GetFreq() != 10 || l.GetTag() != "n" {
+		t.Errorf("LoadWords lost after reload: got %v", l)
+	}
+
+	//反复修改同一词条只保留最后的状态
+	n := len(d.patches)
+	for i := 0; i < 100; i++ {
+		d.DisableWords([]string{"载入49"})
+		d.AddWords([]string{"载入49"})
+	}
+	d.DisableWords([]string{"分词"})
+	if len(d.patches) != n {
+		t.Errorf("patch log grew from %d to %d", n, len(d.patches))
+	}
+	if err = d.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if l := NewIKSegmenter("载入49", WithDictionary(d)).Next(); l == nil || l.GetText() != "载入49" || l.GetFreq() != 10 || l.GetTag() != "n" {
+		t.Errorf("compacted patch after reload: got %v", l)
+	}
+	if d.ContainsWord("分词") || !d.load().disabledWords["分词"] || d.load().disabledWords["载入49"] {
+		t.Errorf("disabled words after compaction: %v", d.load().disabledWords)
+	}
+}
+
+func TestCompiledDictionary(t *testing.T) {
+	d := testDictionary(t)
+	d.DisableWords([]string{"分词", "北京大学"})
+	buf := &bytes.Buffer{}
+	if err := d.WriteCompiled(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "ik.dict")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := LoadCompiledDictionary(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for dictType, name := range compiledDictNames {
+		f, err := os.Open(filepath.Join("dict", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		readDictLines(f, func(word string, freq int, tag string) {
+			if d.ContainsDictWord(dictType, word) != c.ContainsDictWord(dictType, word) {
+				t.Errorf("%s: ContainsDictWord(%s) differs", name, word)
+			}
+		})
+		f.Close()
+	}
+	if got := c.load().disabledWords; len(got) != 2 || !got["分词"] || !got["北京大学"] {
+		t.Errorf("disabled words: got %v", got)
+	}
+
+	texts := []string{
+		"中华人民共和国成立了",
+		"北京大学的学生在百姓网上发布二手房出租信息",
+		"测试分词实例，三个苹果，2017年",
+	}
+	for _, text := range texts {
+		for _, smart := range []bool{true, false} {
+			want := strings.Join(segmentTexts(NewIKSegmenter(text, WithSmart(smart), WithDictionary(d), WithPlaceOrgNames(true))), "/")
+			got := strings.Join(segmentTexts(NewIKSegmenter(text, WithSmart(smart), WithDictionary(c), WithPlaceOrgNames(true))), "/")
+			if got != want {
+				t.Errorf("%s (smart=%v): compiled %s, text %s", text, smart, got, want)
+			}
+		}
+	}
+
+	data := buf.Bytes()
+	data[len(data)-1] ^= 0xff
+	if _, err = ReadCompiledDictionary(data); !errors.Is(err, ErrCompiledDictChecksum) {
+		t.Errorf("corrupted data: got %v", err)
+	}
+	data[8] = 99
+	if _, err = ReadCompiledDictionary(data); !errors.Is(err, ErrCompiledDictVersion) {
+		t.Errorf("unknown version: got %v", err)
+	}
+}
+
+func TestDoubleArrayTrie(t *testing.T) {
+	corpus, err := os.ReadFile("testdata/corpus.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trie := testDictionary(t)
+	dat := testDictionary(t, WithDoubleArrayTrie())
+	if _, ok := dat.load().trie(DICT_TYPE_MAIN).(*doubleArrayTrie); !ok {
+		t.Fatal("double-array trie not selected")
+	}
+
+	for _, line := range strings.Split(string(corpus), "\n") {
+		for _, smart := range []bool{true, false} {
+			want := strings.Join(segmentTexts(NewIKSegmenter(line, WithSmart(smart), WithDictionary(trie))), "/")
+			got := strings.Join(segmentTexts(NewIKSegmenter(line, WithSmart(smart), WithDictionary(dat))), "/")
+			if got != want {
+				t.Errorf("smart=%v:\n got  %s\n want %s", smart, got, want)
+			}
+		}
+	}
+
+	//逐个前缀比对匹配结果
+	for _, word := range []string{"中华人民共和国", "北京大学", "有限公司", "三星手机", "不存在的词", "欧阳", "𠀀𠀁"} {
+		chars := []rune(word)
+		for n := 1; n <= len(chars); n++ {
+			want := trie.load().trie(DICT_TYPE_MAIN).matchSeg(chars, 0, n)
+			got := dat.load().trie(DICT_TYPE_MAIN).matchSeg(chars, 0, n)
+			if got.hitState != want.hitState || got.end != want.end {
+				t.Errorf("%s[:%d]: got state %d end %d, want state %d end %d",
+					word, n, got.hitState, got.end, want.hitState, want.end)
+			}
+		}
+	}
+
+	//运行时修改词条后重建
+	dat.AddWords([]string{"望京SOHO", "𠀀𠀁"})
+	dat.DisableWords([]string{"北京大学"})
+	if !dat.ContainsWord("𠀀𠀁") || dat.ContainsWord("北京大学") {
+		t.Error("patched words not visible")
+	}
+	got := strings.Join(segmentTexts(NewIKSegmenter("𠀀𠀁北京大学", WithSmart(true), WithDictionary(dat), WithPlaceOrgNames(true))), "/")
+	if got != "𠀀𠀁/北京/大学" {
+		t.Errorf("after patch: got %s", got)
+	}
+}
+
+/**
+ * 以"文本@起始位置"的形式输出全部词元
+ */
+func segmentPositions(s *IKSegmenter, shift int) []string {
+	words := []string{}
+	for l := s.Next(); l != nil; l = s.Next() {
+		words = append(words, fmt.Sprintf("%s@%d", l.GetText(), l.GetBeginPosition()+shift))
+	}
+	return words
+}
+
+func TestSegmentReader(t *testing.T) {
+	d := testDictionary(t)
+	corpus, err := os.ReadFile("testdata/corpus.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//换行符两侧互不影响，整篇文本的结果应等于逐行分词结果的拼接
+	lines := []string{}
+	for i := 0; i < 20; i++ {
+		lines = append(lines, strings.Split(strings.TrimSpace(string(corpus)), "\n")...)
+	}
+	for _, smart := range []bool{true, false} {
+		want := []string{}
+		offset := 0
+		for _, line := range lines {
+			want = append(want, segmentPositions(NewIKSegmenter(line, WithSmart(smart), WithDictionary(d)), offset)...)
+			offset += len([]rune(line)) + 1
+		}
+		text := strings.Join(lines, "\n")
+		if offset <= AC_BUFF_SIZE*2 {
+			t.Fatalf("corpus too short: %d runes", offset)
+		}
+		got := segmentPositions(NewIKSegmenterFromReader(iotest.OneByteReader(strings.NewReader(text)), WithSmart(smart), WithDictionary(d)), 0)
+		if strings.Join(got, "/") != strings.Join(want, "/") {
+			t.Errorf("smart=%v: reader result differs from line by line result", smart)
+		}
+	}
+
+	//词语跨越缓冲区临界区及AC_BUFF_SIZE边界
+	tail := "中华人民共和国 windows2000 3.14 北京大学"
+	for _, smart := range []bool{true, false} {
+		want := strings.Join(segmentTexts(NewIKSegmenter(tail, WithSmart(smart), WithDictionary(d))), "/")
+		for pad := AC_BUFF_SIZE - AC_BUFF_EXHAUST_CRITICAL - 10; pad <= AC_BUFF_SIZE+5; pad++ {
+			text := strings.Repeat("测试 ", pad/3) + strings.Repeat(" ", pad%3) + tail
+			s := NewIKSegmenterFromReader(strings.NewReader(text), WithSmart(smart), WithDictionary(d))
+			words := segmentTexts(s)
+			got := strings.Join(words[len(words)-strings.Count(want, "/")-1:], "/")
+			if got != want {
+				t.Errorf("smart=%v pad=%d: got %s, want %s", smart, pad, got, want)
+			}
+		}
+	}
+
+	//ResetReader复用分词器，读取错误通过Err返回
+	s := NewIKSegmenterFromReader(strings.NewReader("测试"), WithSmart(true), WithDictionary(d))
+	segmentTexts(s)
+	readErr := errors.New("broken pipe")
+	s.ResetReader(io.MultiReader(strings.NewReader("分词实例"), iotest.ErrReader(readErr)))
+	if got := strings.Join(segmentTexts(s), "/"); got != "分词/实例" {
+		t.Errorf("after reset: got %s", got)
+	}
+	if !errors.Is(s.Err(), readErr) {
+		t.Errorf("Err: got %v", s.Err())
+	}
+	s.Reset("测试")
+	if got := strings.Join(segmentTexts(s), "/"); got != "测试" || s.Err() != nil {
+		t.Errorf("after Reset: got %s, %v", got, s.Err())
+	}
+}
+
+func TestSegment(t *testing.T) {
+	d := testDictionary(t)
+	tokens := Segment("测试分词实例windows2000", WithSmart(true), WithDictionary(d))
+	want := []Token{
+		{Text: "测试", Start: 0, End: 2, ByteEnd: 6, UTF16End: 2, Type: LEXEME_TYPE_CNWORD},
+		{Text: "分词", Start: 2, End: 4, ByteStart: 6, ByteEnd: 12, UTF16Start: 2, UTF16End: 4, Type: LEXEME_TYPE_CNWORD, Position: 1},
+		{Text: "实例", Start: 4, End: 6, ByteStart: 12, ByteEnd: 18, UTF16Start: 4, UTF16End: 6, Type: LEXEME_TYPE_CNWORD, Position: 2},
+		{Text: "windows2000", Start: 6, End: 17, ByteStart: 18, ByteEnd: 29, UTF16Start: 6, UTF16End: 17, Type: LEXEME_TYPE_LETTER, Position: 3, Tag: POS_TAG_ENGLISH},
+	}
+	if !slices.Equal(tokens, want) {
+		t.Errorf("Segment: got %v", tokens)
+	}
+
+	got := SegmentStrings("中华人民共和国", WithDictionary(d))
+	if !slices.Contains(got, "中华人民共和国") || !slices.Contains(got, "人民") {
+		t.Errorf("SegmentStrings: got %v", got)
+	}
+	if got := SegmentStrings(""); len(got) != 0 {
+		t.Errorf("empty text: got %v", got)
+	}
+
+	//提前结束迭代后可以继续取下一个词元
+	s := NewIKSegmenter("测试分词实例", WithSmart(true), WithDictionary(d))
+	for token := range s.Tokens() {
+		if token.Text != "测试" {
+			t.Errorf("first token: got %s", token.Text)
+		}
+		break
+	}
+	rest := slices.Collect(s.Tokens())
+	if len(rest) != 2 || rest[0].Text != "分词" || rest[1].Text != "实例" || rest[1].Position != 2 {
+		t.Errorf("rest: got %v", rest)
+	}
+}
+
+func TestSegmenterOptions(t *testing.T) {
+	d := testDictionary(t)
+	text := "the 测试 and windows2000"
+
+	got := strings.Join(SegmentStrings(text, WithSmart(true), WithDictionary(d)), "/")
+	if got != "测试/windows2000" {
+		t.Errorf("default: got %s", got)
+	}
+	got = strings.Join(SegmentStrings(text, WithSmart(true), WithDictionary(d), WithStopwords(false)), "/")
+	if got != "the/测试/and/windows2000" {
+		t.Errorf("WithStopwords(false): got %s", got)
+	}
+	got = strings.Join(SegmentStrings(text, WithSmart(true), WithDictionary(d),
+		WithSegmenters(func() ISegmenter { return NewCJKSegmenter() })), "/")
+	if got != "测试" {
+		t.Errorf("WithSegmenters: got %s", got)
+	}
+
+	//缓冲区变小后结果不变
+	corpus, err := os.ReadFile("testdata/corpus.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Segment(string(corpus), WithDictionary(d))
+	for _, size := range []int{64, 100, 257} {
+		tokens := Segment(string(corpus), WithDictionary(d), WithBufferSize(size))
+		if !slices.Equal(tokens, want) {
+			t.Errorf("WithBufferSize(%d): got %d tokens, want %d", size, len(tokens), len(want))
+		}
+	}
+}
+
+// 测试用的股票代码类型
+const lexemeTypeTicker LexemeType = 1000
+
+/**
+ * 识别$AAPL形式的股票代码
+ */
+type tickerSegmenter struct {
+	start int
+}
+
+func (s *tickerSegmenter) Analyze(ctx *SegmentContext) {
+	cursor := ctx.Cursor()
+	if s.start == -1 {
+		if ctx.CurrentRune() == '$' {
+			s.start = cursor
+		}
+	} else if ctx.CurrentCharType() != CHAR_ENGLISH {
+		s.output(ctx, cursor)
+		if ctx.CurrentRune() == '$' {
+			s.start = cursor
+		}
+	}
+	if ctx.IsBufferConsumed() && s.start != -1 {
+		s.output(ctx, cursor+1)
+	}
+	if s.start == -1 {
+		ctx.UnlockBuffer()
+	} else {
+		ctx.LockBuffer()
+	}
+}
+
+func (s *tickerSegmenter) output(ctx *SegmentContext, end int) {
+	if end-s.start > 1 {
+		ctx.AddLexeme(s.start, end-s.start, lexemeTypeTicker)
+	}
+	s.start = -1
+}
+
+func (s *tickerSegmenter) Reset() {
+	s.start = -1
+}
+
+func TestCustomSegmenter(t *testing.T) {
+	d := testDictionary(t)
+	withTicker := WithCustomSegmenters(func() Segmenter { return &tickerSegmenter{start: -1} })
+
+	tokens := Segment("买入$AAPL和$TSLA", WithSmart(true), WithDictionary(d), withTicker)
+	got := []string{}
+	for _, token := range tokens {
+		if token.Type == lexemeTypeTicker {
+			got = append(got, fmt.Sprintf("%s@%d", token.Text, token.Start))
+		}
+	}
+	if strings.Join(got, "/") != "$AAPL@2/$TSLA@8" {
+		t.Errorf("smart: got %v", tokens)
+	}
+
+	//细粒度模式下与内置子分词器的结果并存
+	words := SegmentStrings("$AAPL", WithDictionary(d), withTicker)
+	if !slices.Contains(words, "$AAPL") || !slices.Contains(words, "AAPL") {
+		t.Errorf("max word: got %v", words)
+	}
+
+	//跨越缓冲区
+	text := strings.Repeat("测试 ", 30) + "$GOOG"
+	words = SegmentStrings(text, WithDictionary(d), WithBufferSize(64), withTicker)
+	if !slices.Contains(words, "$GOOG") {
+		t.Errorf("buffer boundary: got %v", words)
+	}
+}
+
+func TestArbitrator(t *testing.T) {
+	d := testDictionary(t)
+	text := "中华人民共和国"
+	if got := strings.Join(SegmentStrings(text, WithSmart(true), WithDictionary(d)), "/"); got != "中华人民共和国" {
+		t.Fatalf("default: got %s", got)
+	}
+
+	//从左到右每次选最短的词
+	paths := []string{}
+	shortest := ArbitratorFunc(func(path *CrossPath) []*Lexeme {
+		paths = append(paths, fmt.Sprintf("%s@%d-%d", path.Text(), path.Begin(), path.End()))
+		chosen := []*Lexeme{}
+		end := -1
+		lexemes := path.Lexemes()
+		for i, l := range lexemes {
+			if l.GetBeginPosition() < end {
+				continue
+			}
+			//同一起点的词元按长度降序排列，取最后一个
+			if i+1 < len(lexemes) && lexemes[i+1].GetBeginPosition() == l.GetBeginPosition() {
+				continue
+			}
+			chosen = append(chosen, l)
+			end = l.GetEndPosition()
+		}
+		return chosen
+	})
+	got := strings.Join(SegmentStrings("测试 "+text, WithSmart(true), WithDictionary(d), WithArbitrator(shortest)), "/")
+	if got != "测试/中华/人民/共和国" {
+		t.Errorf("shortest: got %s", got)
+	}
+	if strings.Join(paths, ",") != "中华人民共和国@3-10" {
+		t.Errorf("cross paths: got %v", paths)
+	}
+
+	//不合法的结果改用默认策略
+	overlapping := ArbitratorFunc(func(path *CrossPath) []*Lexeme {
+		return path.Lexemes()
+	})
+	foreign := ArbitratorFunc(func(path *CrossPath) []*Lexeme {
+		return []*Lexeme{NewLexeme(0, 0, 2, LEXEME_TYPE_CNWORD)}
+	})
+	for name, a := range map[string]Arbitrator{"overlapping": overlapping, "foreign": foreign} {
+		got := strings.Join(SegmentStrings(text, WithSmart(true), WithDictionary(d), WithArbitrator(a)), "/")
+		if got != "中华人民共和国" {
+			t.Errorf("%s: got %s", name, got)
+		}
+	}
+}
+
+func TestMaxProbMode(t *testing.T) {
+	d := NewEmptyDictionary()
+	d.LoadWords(DICT_TYPE_MAIN, strings.NewReader("中国 50000\n国有 3000\n很多 10000\n结合 11520\n合成 2200\n成分 3107\n分子 3370\nhello world\n"))
+
+	cases := []struct {
+		text string
+		mode SegmentMode
+		want string
+	}{
+		{"中国有很多人", SEGMENT_MODE_SMART, "中/国有/很多/人"},
+		{"中国有很多人", SEGMENT_MODE_MAX_PROB, "中国/有/很多/人"},
+		{"中国有很多人", SEGMENT_MODE_MAX_WORD, "中国/国有/很多/人"},
+		{"结合成分子", SEGMENT_MODE_MAX_PROB, "结合/成/分子"},
+	}
+	for _, c := range cases {
+		got := strings.Join(SegmentStrings(c.text, WithMode(c.mode), WithDictionary(d)), "/")
+		if got != c.want {
+			t.Errorf("%s (mode %d): got %s, want %s", c.text, c.mode, got, c.want)
+		}
+	}
+
+	//词频不是整数时整行作为一个词
+	if !d.ContainsWord("hello world") {
+		t.Error("phrase with space not loaded")
+	}
+	if d.load().totalFreq != 50000+3000+10000+11520+2200+3107+3370+WORD_FREQ_DEFAULT {
+		t.Errorf("total freq: got %d", d.load().totalFreq)
+	}
+
+	//编译词典及双数组Trie保留词频
+	buf := &bytes.Buffer{}
+	if err := d.WriteCompiled(buf); err != nil {
+		t.Fatal(err)
+	}
+	c, err := ReadCompiledDictionary(buf.Bytes(), WithDoubleArrayTrie())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(SegmentStrings("中国有很多人", WithMode(SEGMENT_MODE_MAX_PROB), WithDictionary(c)), "/")
+	if got != "中国/有/很多/人" || c.load().totalFreq != d.load().totalFreq {
+		t.Errorf("compiled: got %s, total %d", got, c.load().totalFreq)
+	}
+	hit := c.load().trie(DICT_TYPE_MAIN).matchSeg([]rune("国有"), 0, 2)
+	if !hit.isMatch() || hit.freq != 3000 {
+		t.Errorf("double array freq: got %d", hit.freq)
+	}
+}
+
+func TestTokenOffsets(t *testing.T) {
+	d := testDictionary(t)
+	check := func(text string) {
+		t.Helper()
+		runes := []rune(text)
+		units := utf16.Encode(runes)
+		tokens := Segment(text, WithDictionary(d))
+		if len(tokens) == 0 {
+			t.Fatalf("%q: no tokens", text)
+		}
+		for _, token := range tokens {
+			if got := string(runes[token.Start:token.End]); got != token.Text {
+				t.Errorf("rune offsets %d-%d: got %q, want %q", token.Start, token.End, got, token.Text)
+			}
+			if got := text[token.ByteStart:token.ByteEnd]; got != token.Text {
+				t.Errorf("byte offsets %d-%d: got %q, want %q", token.ByteStart, token.ByteEnd, got, token.Text)
+			}
+			if got := string(utf16.Decode(units[token.UTF16Start:token.UTF16End])); got != token.Text {
+				t.Errorf("utf16 offsets %d-%d: got %q, want %q", token.UTF16Start, token.UTF16End, got, token.Text)
+			}
+		}
+	}
+
+	check("😀测试abc分词🎉实例 iPhone15 𠀀中华人民共和国 👍🏽 北京大学")
+	//跨越多个缓冲区
+	check(strings.Repeat("🎉测试 emoji😀分词实例，", AC_BUFF_SIZE/8))
+
+	//非法UTF-8字节按一个字节计
+	tokens := Segment("\xff测试", WithDictionary(d))
+	if len(tokens) != 1 || tokens[0].ByteStart != 1 || tokens[0].ByteEnd != 7 || tokens[0].UTF16Start != 1 {
+		t.Errorf("invalid utf-8: got %+v", tokens)
+	}
+}
+
+func TestTokenJSON(t *testing.T) {
+	tokens := Segment("测试三十五", WithSmart(true), WithDictionary(testDictionary(t)))
+	data, err := json.Marshal(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"text":"测试","start":0,"end":2,"byte_start":0,"byte_end":6,"utf16_start":0,"utf16_end":2,"type":"CN_WORD","position":0},` +
+		`{"text":"三十五","start":2,"end":5,"byte_start":6,"byte_end":15,"utf16_start":2,"utf16_end":5,"type":"CN_NUM","position":1,"tag":"m","value":"35"}]`
+	if string(data) != want {
+		t.Errorf("Marshal: got %s", data)
+	}
+	var decoded []Token
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(decoded, tokens) {
+		t.Errorf("Unmarshal: got %v", decoded)
+	}
+
+	for lt, name := range lexemeTypeNames {
+		var got LexemeType
+		if err := got.UnmarshalText([]byte(name)); err != nil || got != lt || lt.String() != name {
+			t.Errorf("%d: %s round trip got %d, %v", lt, name, got, err)
+		}
+	}
+	var lt LexemeType
+	if err := lt.UnmarshalText([]byte("TYPE_CNUM")); err != nil || lt != LEXEME_TYPE_CNUM {
+		t.Errorf("legacy name: got %v, %v", lt, err)
+	}
+	if err := lt.UnmarshalText([]byte("NOUN")); err == nil {
+		t.Error("unknown name: expected error")
+	}
+	if LexemeType(5).String() != "UNKNOWN" {
+		t.Errorf("unknown value: got %s", LexemeType(5))
+	}
+}
+
+/**
+ * 加载基准测试使用的词典
+ * 默认为只有几百个词的测试词典，可以用环境变量IKGO_BENCH_DICT指定完整的IK词典目录
+ */
+func benchDictionary(b *testing.B, opts ...LoadOption) *Dictionary {
+	dir := os.Getenv("IKGO_BENCH_DICT")
+	if dir == "" {
+		return testDictionary(b, opts...)
+	}
+	d, err := LoadDictionary(dir, opts...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return d
+}
+
+/**
+ * 读取基准测试使用的语料
+ * 默认为十行的testdata/corpus.txt，可以用环境变量IKGO_BENCH_CORPUS指定大语料文件
+ */
+func benchCorpus(b *testing.B) []byte {
+	name := os.Getenv("IKGO_BENCH_CORPUS")
+	if name == "" {
+		name = "testdata/corpus.txt"
+	}
+	corpus, err := os.ReadFile(name)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return corpus
+}
+
+func BenchmarkLoadTextDictionary(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		benchDictionary(b)
+	}
+}
+
+func BenchmarkLoadCompiledDictionary(b *testing.B) {
+	buf := &bytes.Buffer{}
+	benchDictionary(b).WriteCompiled(buf)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ReadCompiledDictionary(buf.Bytes()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkSegment(b *testing.B, opts ...LoadOption) {
+	corpus := benchCorpus(b)
+	d := benchDictionary(b, opts...)
+	text := string(corpus)
+	b.SetBytes(int64(len(corpus)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s := NewIKSegmenter(text, WithSmart(false), WithDictionary(d))
+		for l := s.Next(); l != nil; l = s.Next() {
+		}
+	}
+}
+
+func BenchmarkSegmentDictSegment(b *testing.B) {
+	benchmarkSegment(b)
+}
+
+func BenchmarkSegmentDoubleArray(b *testing.B) {
+	benchmarkSegment(b, WithDoubleArrayTrie())
+}
+
+/**
+ * 模拟CJKSegmenter在每个位置上的前缀匹配
+ */
+func benchmarkMatch(b *testing.B, opts ...LoadOption) {
+	chars := []rune(string(benchCorpus(b)))
+	root := benchDictionary(b, opts...).load().trie(DICT_TYPE_MAIN)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for begin := range chars {
+			hit := root.matchSeg(chars, begin, 1)
+			for end := begin + 1; hit.isPrefix() && end < len(chars); end++ {
+				hit.matchedNode.matchSegSearch(chars, end, 1, hit)
+			}
+		}
+	}
+}
+
+func BenchmarkMatchDictSegment(b *testing.B) {
+	benchmarkMatch(b)
+}
+
+func BenchmarkMatchDoubleArray(b *testing.B) {
+	benchmarkMatch(b, WithDoubleArrayTrie())
+}
+
+func BenchmarkBuildDoubleArray(b *testing.B) {
+	root := benchDictionary(b).load().mainDict
+	b.ReportAllocs()
+	b.ResetTimer()
+	var t *doubleArrayTrie
+	for i := 0; i < b.N; i++ {
+		t = newDoubleArrayTrie(root)
+	}
+	b.StopTimer()
+	//构建结果的大小：状态数及各数组占用的字节数
+	size := len(t.base)*4 + len(t.check)*4 + len(t.freqs)*4 + len(t.tags)*2 + len(t.flags) + len(t.bmp)*4
+	b.ReportMetric(float64(len(t.check)), "states")
+	b.ReportMetric(float64(size), "trie-bytes")
+}
+
+func TestHMM(t *testing.T) {
+	d := testDictionary(t)
+	text := "我们在杭州吃了东坡肉"
+
+	//未开启时未登录的部分按单字输出
+	plain := Segment(text, WithSmart(true), WithDictionary(d))
+	oov := Segment(text, WithSmart(true), WithDictionary(d), WithHMM(true))
+	var plainText, oovText strings.Builder
+	for _, tok := range plain {
+		if tok.Type == LEXEME_TYPE_OOV {
+			t.Errorf("unexpected oov token %s without hmm", tok.Text)
+		}
+		plainText.WriteString(tok.Text)
+	}
+	found := false
+	for _, tok := range oov {
+		found = found || tok.Type == LEXEME_TYPE_OOV
+		oovText.WriteString(tok.Text)
+	}
+	if !found || oovText.String() != plainText.String() {
+		t.Errorf("hmm: got %v", oov)
+	}
+
+	//自定义模型
+	model, err := LoadHMMModel(strings.NewReader(`# 东坡肉为词首、词中、词尾
+start B -0.7
+start S -0.7
+trans B M -0.7
+trans B E -0.7
+trans M M -0.7
+trans M E -0.7
+trans E B -0.7
+trans E S -0.7
+trans S B -0.7
+trans S S -0.7
+emit B * -10
+emit M * -10
+emit E * -10
+emit S * -1
+emit B 东 -0.1
+emit M 坡 -0.1
+emit E 肉 -0.1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(SegmentStrings("吃了东坡肉", WithDictionary(d), WithHMMModel(model), WithHMM(true)), "/")
+	if got != "吃/了/东坡肉" {
+		t.Errorf("custom model: got %s", got)
+	}
+
+	//写出后读回的模型切分结果一致
+	buf := &bytes.Buffer{}
+	if _, err := NewHMMModelFromDictionary(d).WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadHMMModel(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join(SegmentStrings(text, WithSmart(true), WithDictionary(d), WithHMM(true)), "/")
+	got = strings.Join(SegmentStrings(text, WithSmart(true), WithDictionary(d), WithHMM(true), WithHMMModel(loaded)), "/")
+	if got != want {
+		t.Errorf("round trip: got %s, want %s", got, want)
+	}
+
+	if _, err := LoadHMMModel(strings.NewReader("emit X 字 -1\n")); err == nil {
+		t.Error("expected error for unknown state")
+	}
+}
+
+func TestPOSTag(t *testing.T) {
+	d := NewEmptyDictionary()
+	d.LoadWords(DICT_TYPE_MAIN, strings.NewReader("我们 5000 r\n喜欢 3000 v\n吃 2000 v\n肉 1000 n\n东坡\n"))
+
+	tags := func(text string, dict *Dictionary) string {
+		var got []string
+		for _, tok := range Segment(text, WithSmart(true), WithDictionary(dict), WithHMM(true)) {
+			got = append(got, tok.Text+"/"+tok.Tag)
+		}
+		return strings.Join(got, " ")
+	}
+	want := "我们/r 喜欢/v 吃/v 东坡/ 肉/n 2/m 斤/x iphone/eng"
+	if got := tags("我们喜欢吃东坡肉2斤iphone", d); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	//编译词典及双数组Trie保留词性
+	buf := &bytes.Buffer{}
+	if err := d.WriteCompiled(buf); err != nil {
+		t.Fatal(err)
+	}
+	c, err := ReadCompiledDictionary(buf.Bytes(), WithDoubleArrayTrie())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tags("我们喜欢吃东坡肉2斤iphone", c); got != want {
+		t.Errorf("compiled: got %s, want %s", got, want)
+	}
+
+	//未登录词取同一词尾字的词最常见的词性
+	d.LoadWords(DICT_TYPE_MAIN, strings.NewReader("燃烧 100 v\n发烧 30 n\n"))
+	model, err := LoadHMMModel(strings.NewReader("start B 0\ntrans B E 0\nemit B * 0\nemit E * 0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tok := range Segment("红烧", WithDictionary(d), WithHMMModel(model)) {
+		if tok.Text != "红烧" || tok.Type != LEXEME_TYPE_OOV || tok.Tag != "v" {
+			t.Errorf("oov: got %v", tok)
+		}
+	}
+
+	//省略词频直接给出词性
+	d.LoadWords(DICT_TYPE_MAIN, strings.NewReader("苏堤 ns\nwindows xp\n"))
+	if tokens := Segment("苏堤", WithDictionary(d)); len(tokens) != 1 || tokens[0].Tag != "ns" {
+		t.Errorf("word tag: got %v", tokens)
+	}
+	if !d.ContainsWord("windows xp") {
+		t.Error("ascii phrase should be kept as one word")
+	}
+}
+
+func TestPersonName(t *testing.T) {
+	d := testDictionary(t)
+	d.AddWords([]string{"高明"})
+	cases := []struct {
+		text string
+		want string
+	}{
+		{"欧阳修文是一位作家", "欧阳修文"},
+		{"张建国和李明在北京大学见面", "张建国/李明"},
+		{"请联系王先生或张女士", ""},
+		{"高兴地说", ""},
+		{"高明的手段", ""},
+	}
+	persons := func(text string, opts ...Option) string {
+		var names []string
+		for _, tok := range Segment(text, append([]Option{WithSmart(true), WithDictionary(d), WithPersonNames(true)}, opts...)...) {
+			if tok.Type == LEXEME_TYPE_PERSON {
+				names = append(names, tok.Text)
+			}
+		}
+		return strings.Join(names, "/")
+	}
+	for _, c := range cases {
+		if got := persons(c.text); got != c.want {
+			t.Errorf("%s: got %s, want %s", c.text, got, c.want)
+		}
+		//人名跨越缓冲区末尾时等待读入后续文本
+		if got := persons(c.text, WithBufferSize(4)); got != c.want {
+			t.Errorf("%s with small buffer: got %s, want %s", c.text, got, c.want)
+		}
+		if got := persons(c.text, WithPersonNames(false)); got != "" {
+			t.Errorf("%s disabled: got %s", c.text, got)
+		}
+	}
+	//默认不识别人名
+	for _, tok := range Segment("张建国和李明", WithSmart(true), WithDictionary(d)) {
+		if tok.Type == LEXEME_TYPE_PERSON {
+			t.Errorf("default: got %v", tok)
+		}
+	}
+
+	//主词典中有nr人名时按统计的名字用字识别，"伟"虽在Given_Name_Chars中，但没有出现在人名中
+	named := NewEmptyDictionary()
+	named.LoadWords(DICT_TYPE_SURNAME, strings.NewReader("王\n李\n张\n"))
+	named.LoadWords(DICT_TYPE_MAIN, strings.NewReader("王建国 100 nr\n李明 50 nr\n"))
+	probs := named.load().givenNameProbs()
+	if len(probs) != 3 || probs['建'] != 0.4 || probs['明'] != 0.2 {
+		t.Errorf("probs: got %v", probs)
+	}
+	var got []string
+	for _, tok := range Segment("张明和张伟", WithSmart(true), WithDictionary(named), WithPersonNames(true)) {
+		if tok.Type == LEXEME_TYPE_PERSON {
+			got = append(got, tok.Text)
+		}
+	}
+	if strings.Join(got, "/") != "张明" {
+		t.Errorf("statistics: got %v", got)
+	}
+}
+
+func TestPlaceOrg(t *testing.T) {
+	d := testDictionary(t)
+	cases := []struct {
+		text      string
+		subTokens bool
+		want      string
+	}{
+		{"北京朝阳区望京附近", false, "北京朝阳区/PLACE 望/CN_CHAR 京/CN_CHAR 附近/CN_WORD"},
+		{"他在海淀区中关村工作", false, "他/CN_CHAR 在/CN_CHAR 海淀区/CN_WORD 中关村/PLACE 工作/CN_WORD"},
+		{"海淀区中关村", true, "海淀区/CN_WORD 中关村/PLACE 中/CN_CHAR 关/CN_CHAR 村/CN_CHAR"},
+		{"陀思妥耶夫斯基", true, "陀思妥耶夫斯基/PERSON 陀/CN_CHAR 思/CN_CHAR 妥/CN_CHAR 耶/CN_CHAR 夫/CN_CHAR 斯基/CN_WORD"},
+		{"一条马路", false, "一条/CN_QUAN 马/CN_CHAR 路/CN_CHAR"},
+		{"他们是一个好公司", false, "他/CN_CHAR 们/CN_CHAR 是/CN_CHAR 一个/CN_WORD 好/CN_CHAR 公司/CN_WORD"},
+	}
+	for _, c := range cases {
+		var got []string
+		for _, tok := range Segment(c.text, WithSmart(true), WithDictionary(d), WithSubTokens(c.subTokens), WithPlaceOrgNames(true), WithPersonNames(true)) {
+			got = append(got, tok.Text+"/"+tok.Type.String())
+		}
+		if strings.Join(got, " ") != c.want {
+			t.Errorf("%s: got %s, want %s", c.text, strings.Join(got, " "), c.want)
+		}
+	}
+	//音译人名随人名识别一起关闭
+	for _, tok := range Segment("陀思妥耶夫斯基", WithSmart(true), WithDictionary(d), WithPlaceOrgNames(true)) {
+		if tok.Type == LEXEME_TYPE_PERSON {
+			t.Errorf("person names disabled: got %v", tok)
+		}
+	}
+	//默认不识别地名、机构名
+	for _, tok := range Segment("北京朝阳区望京附近", WithSmart(true), WithDictionary(d)) {
+		if tok.Type == LEXEME_TYPE_PLACE {
+			t.Errorf("default: got %v", tok)
+		}
+	}
+	got := SegmentStrings("百姓网络科技有限公司", WithSmart(true), WithDictionary(d), WithPlaceOrgNames(true), WithSubTokens(true))
+	if strings.Join(got, "/") != "百姓网络科技有限公司/百姓/网络/科技/有限公司" {
+		t.Errorf("sub tokens: got %v", got)
+	}
+	if tokens := Segment("上海市", WithSmart(true), WithDictionary(d), WithPlaceOrgNames(true)); len(tokens) != 1 || tokens[0].Tag != POS_TAG_PLACE {
+		t.Errorf("tag: got %v", tokens)
+	}
+
+	//未给出词性的后缀只有Org_Suffix_Words中的按机构名处理
+	untagged := NewEmptyDictionary()
+	untagged.LoadWords(DICT_TYPE_SUFFIX, strings.NewReader("大学\n区\n"))
+	for text, want := range map[string]string{"浙江大学": "浙江大学/ORG", "朝阳区": "朝/CN_CHAR 阳/CN_CHAR 区/CN_CHAR"} {
+		var got []string
+		for _, tok := range Segment(text, WithSmart(true), WithDictionary(untagged), WithPlaceOrgNames(true)) {
+			got = append(got, tok.Text+"/"+tok.Type.String())
+		}
+		if strings.Join(got, " ") != want {
+			t.Errorf("untagged %s: got %s, want %s", text, strings.Join(got, " "), want)
+		}
+	}
+}
+
+func TestArbitrationCorpus(t *testing.T) {
+	d := testDictionary(t)
+	corpus, err := os.ReadFile("testdata/arbitration.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := 0
+	for _, line := range strings.Split(string(corpus), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			t.Fatalf("malformed line %q", line)
+		}
+		legacy := strings.Join(SegmentStrings(fields[0], WithSmart(true), WithDictionary(d), WithArbitrator(IKArbitrator{IgnorePrepositions: true})), "/")
+		if legacy != fields[1] {
+			t.Errorf("%s without prepositions: got %s, want %s", fields[0], legacy, fields[1])
+		}
+		got := strings.Join(SegmentStrings(fields[0], WithSmart(true), WithDictionary(d)), "/")
+		if got != fields[2] {
+			t.Errorf("%s: got %s, want %s", fields[0], got, fields[2])
+		}
+		if fields[1] != fields[2] {
+			changed++
+		}
+	}
+	if changed == 0 {
+		t.Error("corpus has no sentence affected by the preposition dictionary")
+	}
+
+	//停止词词典
+	sd := NewEmptyDictionary()
+	sd.LoadWords(DICT_TYPE_MAIN, strings.NewReader("出现\n现在\n"))
+	sd.LoadWords(DICT_TYPE_STOP, strings.NewReader("在\n"))
+	a := IKArbitrator{IgnorePrepositions: true, UseStopwords: true}
+	got := strings.Join(SegmentStrings("出现在", WithSmart(true), WithDictionary(sd), WithArbitrator(a), WithStopwords(false)), "/")
+	if got != "出现/在" {
+		t.Errorf("stopwords: got %s", got)
+	}
+}
+
+func TestQuantifier(t *testing.T) {
+	d := testDictionary(t)
+	//IK的量词词典不含英文单位
+	d.AddDictWords(DICT_TYPE_QUANTIFIER, []string{"kg", "mg", "ml", "gb"})
+	cases := []struct {
+		text   string
+		policy QuantifierPolicy
+		smart  bool
+		want   string
+	}{
+		//与Java版IK Analyzer的结果一致
+		{"三星手机", QUANTIFIER_POLICY_IK, true, "三星/CN_WORD 手机/CN_WORD"},
+		{"2个苹果", QUANTIFIER_POLICY_IK, true, "2个/CN_QUAN 苹果/CN_WORD"},
+		{"2个苹果", QUANTIFIER_POLICY_IK, false, "2/ARABIC 个/COUNT 苹果/CN_WORD"},
+		{"月租5800元", QUANTIFIER_POLICY_IK, true, "月/CN_CHAR 租/CN_CHAR 5800元/CN_QUAN"},
+		{"5kg大米", QUANTIFIER_POLICY_IK, true, "5kg/LETTER 大/CN_CHAR 米/CN_CHAR"},
+
+		{"3.5元", QUANTIFIER_POLICY_SPLIT, true, "3.5/ARABIC 元/COUNT"},
+		{"5kg", QUANTIFIER_POLICY_SPLIT, true, "5/ARABIC kg/COUNT"},
+		{"5KG", QUANTIFIER_POLICY_MERGE, true, "5KG/CN_QUAN"},
+		{"100平米", QUANTIFIER_POLICY_MERGE, true, "100平米/CN_QUAN"},
+		{"100平米", QUANTIFIER_POLICY_MERGE, false, "100平米/CN_QUAN 100/ARABIC 平米/COUNT 米/COUNT"},
+		{"5g手机", QUANTIFIER_POLICY_MERGE, true, "5g/LETTER 手机/CN_WORD"},
+	}
+	for _, c := range cases {
+		var got []string
+		for _, tok := range Segment(c.text, WithSmart(c.smart), WithDictionary(d), WithQuantifierPolicy(c.policy)) {
+			got = append(got, tok.Text+"/"+tok.Type.String())
+		}
+		if strings.Join(got, " ") != c.want {
+			t.Errorf("%s (policy %d, smart %v): got %s, want %s", c.text, c.policy, c.smart, strings.Join(got, " "), c.want)
+		}
+	}
+}
+
+func TestChineseNumber(t *testing.T) {
+	cases := []struct {
+		text string
+		want string
+		err  error
+	}{
+		{"一百二十三", "123", nil},
+		{"十五", "15", nil},
+		{"一百零五", "105", nil},
+		{"两万五", "25000", nil},
+		{"三百五", "350", nil},
+		{"贰佰零伍", "205", nil},
+		{"廿三", "23", nil},
+		{"卅", "30", nil},
+		{"三万亿", "3000000000000", nil},
+		{"一亿两千万", "120000000", nil},
+		{"二〇〇八", "2008", nil},
+		{"零零七", "007", nil},
+		{"三点一四", "3.14", nil},
+		{"三点五万", "35000", nil},
+		{"十点五", "10.5", nil},
+		{"一百点五", "100.5", nil},
+		{"一百二十点五", "120.5", nil},
+		{"一百二十点五万", "1205000", nil},
+		{"一万点五万", "", ErrNumeralMalformed},
+		{"十十", "", ErrNumeralMalformed},
+		{"五万三千万", "", ErrNumeralMalformed},
+		{"点五", "", ErrNumeralMalformed},
+		{"九百亿亿", "", ErrNumeralMalformed},
+		{"一千万亿亿", "", ErrNumeralMalformed},
+		{"九百万亿兆", "", ErrNumeralOverflow},
+		{"一二三四五六七八九零一二三四五六七八九零", "", ErrNumeralOverflow},
+	}
+	for _, c := range cases {
+		got, err := ParseChineseNumber(c.text)
+		if got != c.want || err != c.err {
+			t.Errorf("%s: got %q, %v, want %q, %v", c.text, got, err, c.want, c.err)
+		}
+	}
+
+	d := testDictionary(t)
+	format := func(tokens []Token) string {
+		var got []string
+		for _, tok := range tokens {
+			got = append(got, tok.Text+"/"+tok.Value)
+		}
+		return strings.Join(got, " ")
+	}
+	text := "一百二十三个苹果"
+	if got, want := format(Segment(text, WithSmart(true), WithDictionary(d))), "一百二十三个/123 苹果/"; got != want {
+		t.Errorf("value: got %s, want %s", got, want)
+	}
+	if got, want := format(Segment(text, WithSmart(true), WithDictionary(d), WithNumeralFilter(true))), "123个/123 苹果/"; got != want {
+		t.Errorf("filter: got %s, want %s", got, want)
+	}
+	if got, want := format(Segment(text, WithDictionary(d), WithNumeralFilter(true))), "123/123 个/ 苹果/"; got != want {
+		t.Errorf("max word filter: got %s, want %s", got, want)
+	}
+	//小数
+	for text, want := range map[string]string{"十点五": "十点五/10.5", "三点五个": "三点五个/3.5"} {
+		if got := format(Segment(text, WithSmart(true), WithDictionary(d))); got != want {
+			t.Errorf("decimal %s: got %s, want %s", text, got, want)
+		}
+	}
+	//阿拉伯数字后跟中文单位
+	for text, want := range map[string]string{
+		"5千元":   "5千元/5000",
+		"三万元":   "三万元/30000",
+		"3.5万元": "3.5万元/35000",
+		"5千万":   "5千万/50000000",
+		"20万吨":  "20万吨/200000",
+	} {
+		if got := format(Segment(text, WithSmart(true), WithDictionary(d))); got != want {
+			t.Errorf("arabic %s: got %s, want %s", text, got, want)
+		}
+	}
+	if got, want := format(Segment("3.5万元", WithSmart(true), WithDictionary(d), WithNumeralFilter(true))), "35000元/35000"; got != want {
+		t.Errorf("arabic filter: got %s, want %s", got, want)
+	}
+}
+
+func TestTemporal(t *testing.T) {
+	d := testDictionary(t)
+	//2017年8月8日，星期二
+	ref := time.Date(2017, 8, 8, 10, 0, 0, 0, time.Local)
+	cases := []struct {
+		text string
+		want string
+	}{
+		{"2017年8月8日看房", "2017年8月8日/DATE/2017-08-08"},
+		{"二〇一七年八月", "二〇一七年八月/DATE/2017-08"},
+		{"8月8号", "8月8号/DATE/2017-08-08"},
+		{"2017-8-8", "2017-8-8/DATE/2017-08-08"},
+		{"下周五", "下周五/DATE/2017-08-18"},
+		{"上个星期三", "上个星期三/DATE/2017-08-02"},
+		{"这周末", "这周末/DATE/2017-08-12/2017-08-13"},
+		{"下周", "下周/DATE/2017-W33"},
+		{"下午三点半见面", "下午三点半/TIME/T15:30/AF"},
+		{"周五晚上有空", "周五晚上/TIME/2017-08-11/EV"},
+		{"晚上有空", "晚上/TIME//EV"},
+		{"明天下午3:30到", "明天下午3:30/TIME/2017-08-09T15:30/AF"},
+		{"今晚八点", "今晚八点/TIME/2017-08-08T20:00/EV"},
+		{"今晚12点", "今晚12点/TIME/2017-08-09T00:00/EV"},
+		{"晚上十二点", "晚上十二点/TIME/T00:00/EV"},
+		{"中午12点", "中午12点/TIME/T12:00/MI"},
+		{"三点十五分", "三点十五分/TIME/T03:15"},
+		{"三点二十出发", "三点二十/TIME/T03:20"},
+		{"3点钟", "3点钟/TIME/T03:00"},
+		{"2017年", "2017年/DATE/2017"},
+		{"1840年8月", "1840年8月/DATE/1840-08"},
+		{"等了1000年", "1000年/DURATION/P1000Y"},
+		{"一千年", "一千年/DURATION/P1000Y"},
+		{"10点10分30秒", "10点10分30秒/TIME/T10:10:30"},
+		{"租期3天", "3天/DURATION/P3D"},
+		{"一个半小时", "一个半小时/DURATION/PT1.5H"},
+		{"两年半", "两年半/DURATION/P2.5Y"},
+		{"3个月", "3个月/DURATION/P3M"},
+		{"便宜一点", ""},
+		{"一时半会", ""},
+		{"两点之间", ""},
+		{"三点建议", ""},
+		{"3点建议", ""},
+		{"三点一四", ""},
+		{"第三天", ""},
+		{"iphone6天", ""},
+	}
+	temporal := func(text string, opts ...Option) string {
+		var got []string
+		opts = append([]Option{WithSmart(true), WithDictionary(d), WithReferenceTime(ref), WithTemporal(true)}, opts...)
+		for _, tok := range Segment(text, opts...) {
+			switch tok.Type {
+			case LEXEME_TYPE_DATE, LEXEME_TYPE_TIME, LEXEME_TYPE_DURATION:
+				text := tok.Text + "/" + tok.Type.String() + "/" + tok.Value
+				if tok.Period != "" {
+					text += "/" + tok.Period
+				}
+				got = append(got, text)
+			}
+		}
+		return strings.Join(got, " ")
+	}
+	for _, c := range cases {
+		if got := temporal(c.text); got != c.want {
+			t.Errorf("%s: got %s, want %s", c.text, got, c.want)
+		}
+		//时间表达式跨越缓冲区临界区时等待读入后续文本
+		if got := temporal("看看看看看"+c.text, WithBufferSize(16)); got != c.want {
+			t.Errorf("%s with small buffer: got %s, want %s", c.text, got, c.want)
+		}
+		if got := temporal(c.text, WithTemporal(false)); got != "" {
+			t.Errorf("%s disabled: got %s", c.text, got)
+		}
+		//默认不识别
+		for _, tok := range Segment(c.text, WithSmart(true), WithDictionary(d)) {
+			if tok.Type&(LEXEME_TYPE_DATE|LEXEME_TYPE_TIME|LEXEME_TYPE_DURATION) != 0 {
+				t.Errorf("%s default: got %v", c.text, tok)
+			}
+		}
+	}
+}
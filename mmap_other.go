@@ -0,0 +1,17 @@
+//go:build !unix
+
+package ikgo
+
+import "os"
+
+/**
+ * 不支持mmap的平台直接读取整个文件
+ * @return data 文件内容, release 释放函数
+ */
+func mapFile(path string) (data []byte, release func(), err error) {
+	data, err = os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() {}, nil
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package ikgo
+
+import (
+	"os"
+	"syscall"
+)
+
+/**
+ * 以只读方式映射文件
+ * @return data 文件内容, release 解除映射
+ */
+func mapFile(path string) (data []byte, release func(), err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	size := fi.Size()
+	if size == 0 || int64(int(size)) != size {
+		//空文件无法映射，交由解码器报告格式错误
+		return []byte{}, func() {}, nil
+	}
+
+	data, err = syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() {
+		syscall.Munmap(data)
+	}, nil
+}